@@ -2,7 +2,8 @@
 package aggressiveAi
 
 import (
-    common "github.com/zond/stockholm-ai/common"
+    common "github.com/miridius/ai/common"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
     state "github.com/zond/stockholm-ai/state"
     "sort"
 )
@@ -55,12 +56,53 @@ type ByDist struct{ PathQueue }
 
 func (s ByDist) Less(i, j int) bool { return s.PathQueue[i].length < s.PathQueue[j].length }
 
+// returns the delays in a map of delay to number of soldiers, soonest first
+func sortedDelays(availables map[int]int) (result []int) {
+    result = make([]int, 0, len(availables))
+    for delay := range availables {
+        result = append(result, delay)
+    }
+    sort.Ints(result)
+    return
+}
+
 /*
-Orders will analyze all nodes in s and return orders for each one
+defence decides how many enemy units count as being on a node and how many of my units must stay home to hold it.
+It is the only thing that differs between AggressiveAi1 and AggressiveAi2.
 */
-func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *state.State) (result state.Orders) {
+type defence interface {
+    enemyUnits(node *state.Node) int
+    defenders(node *state.Node, units int) int
+}
+
+// v1 defence: only look at the units on the node itself and always leave 1 guy home
+type holdOne struct {
+    me state.PlayerId
+}
 
+func (self holdOne) enemyUnits(node *state.Node) (result int) {
+    for playerId, numUnits := range node.Units {
+        if playerId != self.me {
+            result += numUnits
+        }
+    }
+    return
+}
+
+func (self holdOne) defenders(node *state.Node, units int) int {
+    return 1
+}
+
+/*
+Orders will analyze all nodes in s and return orders for each one
+*/
+func (self AggressiveAi1) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) state.Orders {
     logger.Printf("AggressiveAi1 calculating orders for player: %v", me)
+    return aggressiveOrders(logger, me, s, holdOne{me})
+}
+
+// the aggressive algorithm, with the given way of deciding how many soldiers to leave home
+func aggressiveOrders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State, defence defence) (result state.Orders) {
 
     //list of all nodes I don't own and haven't sent guys to yet
     unclaimed := make([]state.NodeId, 0, len(s.Nodes))
@@ -74,52 +116,35 @@ func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *sta
     //total available guys across all nodes
     totalAvailable := 0
 
-    //map of source and destination node IDs (concatenated together) to the length of the path between those nodes
-    shortestPaths := make(map[state.NodeId]int)
+    //shortest paths between all nodes, only calculated once per map
+    distances := common.GetTopology(s).Distances
+
+    //all node IDs in a fixed order, so that the same state always gives the same orders
+    nodeIds := common.SortedNodeIds(s)
 
-    // iterate over all nodes in order to populate the unclaimed list, enemy list, available soldiers map, and shortest paths map
-    for _, node := range s.Nodes {
-        enemyUnits := 0
-        units := 0
+    // iterate over all nodes in order to populate the unclaimed list, enemy list and available soldiers map
+    for _, nodeId := range nodeIds {
+        node := s.Nodes[nodeId]
         // count enemy and friendly units
-        for playerId, numUnits := range node.Units {
-            if playerId == me {
-                units += numUnits
-            } else {
-                enemyUnits += numUnits
-            }
-        }
+        enemyUnits := defence.enemyUnits(node)
+        units := node.Units[me]
         //logger.Printf("totalAvailable: %v", totalAvailable)
         //logger.Printf("node: %v   has units: %v  enemyUnits: %v", node.Id, units, enemyUnits)
         // check for enemy node
         if enemyUnits > 0 {
             enemy = append(enemy, node.Id)
-            for src := range allAvailable {
-                shortestPaths[src+node.Id] = len(s.Path(src, node.Id, nil))
-            }
             // check for unclaimed node
         } else if units <= 0 {
             unclaimed = append(unclaimed, node.Id)
-            // find shortest paths from all available guys to this new unclaimed node
-            for src := range allAvailable {
-                shortestPaths[src+node.Id] = len(s.Path(src, node.Id, nil))
-            }
         }
         // check for available units on node itself
-        if units > 1 {
-            // always leave 1 home to keep ownership of the node
-            units--
+        if keep := defence.defenders(node, units); units > keep {
+            // leave enough guys home to keep ownership of the node
+            units -= keep
             // is this node new to us?
             if len(allAvailable[node.Id]) == 0 {
                 // create map
                 allAvailable[node.Id] = map[int]int{0: units}
-                // find shortest path from this new node to all unclaimed and enemy nodes
-                for _, dst := range unclaimed {
-                    shortestPaths[node.Id+dst] = len(s.Path(node.Id, dst, nil))
-                }
-                for _, dst := range enemy {
-                    shortestPaths[node.Id+dst] = len(s.Path(node.Id, dst, nil))
-                }
             } else {
                 // add key to map
                 allAvailable[node.Id][0] += units
@@ -128,7 +153,7 @@ func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *sta
             //logger.Printf("totalAvailable (from node): %v", totalAvailable)
         }
         // check for available units on each edge leaving from this node
-        for _, edge := range node.Edges {
+        for _, edge := range common.SortedEdges(node) {
             for index, unitMap := range edge.Units {
                 if units = unitMap[me]; units > 0 {
                     delay := len(edge.Units) - index
@@ -136,10 +161,6 @@ func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *sta
                     if len(allAvailable[edge.Dst]) == 0 {
                         // create map
                         allAvailable[edge.Dst] = map[int]int{delay: units}
-                        // find shortest path from this new node to all unclaimed nodes
-                        for _, dst := range unclaimed {
-                            shortestPaths[edge.Dst+dst] = len(s.Path(edge.Dst, dst, nil))
-                        }
                     } else {
                         // add key to map
                         allAvailable[edge.Dst][delay] += units
@@ -157,7 +178,11 @@ func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *sta
     logger.Printf("totalAvailable: %v", totalAvailable)
     //logger.Printf("allAvailable: %v", len(allAvailable))
     count := 0
-    for src, availables := range allAvailable {
+    for _, src := range nodeIds {
+        availables, found := allAvailable[src]
+        if !found {
+            continue
+        }
         for _, units := range availables {
             count += units
         }
@@ -173,10 +198,11 @@ func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *sta
             length: -1,
         }
         logger.Printf("finding best available for: %v", node)
-        for src, availables := range allAvailable {
-            dist := shortestPaths[src+node]
-            for delay, units := range availables {
-                if units > 0 && (best.length < 0 || dist+delay < best.length) {
+        for _, src := range nodeIds {
+            availables := allAvailable[src]
+            dist := distances.Distance(src, node)
+            for _, delay := range sortedDelays(availables) {
+                if units := availables[delay]; units > 0 && (best.length < 0 || dist+delay < best.length) {
                     best.src = src
                     best.delay = delay
                     best.length = dist + delay
@@ -205,7 +231,7 @@ func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *sta
             if next.delay == 0 {
                 result = append(result, state.Order{
                     Src:   next.src,
-                    Dst:   s.Path(next.src, next.dst, nil)[0],
+                    Dst:   distances.NextHop(next.src, next.dst),
                     Units: 1,
                 })
                 //              logger.Printf("sending 1 soldier from: %v  towards: %v", next.src, next.dst)
@@ -216,10 +242,12 @@ func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *sta
                 dst:    next.dst,
                 length: -1,
             }
-            for src, availables := range allAvailable {
-                dist := shortestPaths[src+next.dst]
+            for _, src := range nodeIds {
+                availables := allAvailable[src]
+                dist := distances.Distance(src, next.dst)
                 //logger.Printf("src: %v  availables: %v  dist: %v", src, availables, dist)
-                for delay, units := range availables {
+                for _, delay := range sortedDelays(availables) {
+                    units := availables[delay]
                     //logger.Printf("delay: %v  units: %v", delay, units)
                     if units > 0 && (best.length < 0 || dist+delay < best.length) {
                         best.src = src
@@ -238,21 +266,17 @@ func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *sta
 
     // send remaining available units to closest node that has any enemy units on it
     if totalAvailable > 0 {
-        for src, availables := range allAvailable {
-            if units := availables[0]; units > 0 {
+        for _, src := range nodeIds {
+            if units := allAvailable[src][0]; units > 0 {
                 //find closest enemy node
                 //              logger.Printf("finding closest enemy for %v available guys on %v", units, src)
                 best := -1
                 bestEdge := src
                 for _, dst := range enemy {
-                    if dst == src {
-                        best = 0
-                        bestEdge = src
-                    }
-                    dist := shortestPaths[src+dst]
+                    dist := distances.Distance(src, dst)
                     if best < 0 || dist < best {
                         best = dist
-                        bestEdge = s.Path(src, dst, nil)[0]
+                        bestEdge = distances.NextHop(src, dst)
                     }
                 }
                 if bestEdge == src {
@@ -269,6 +293,12 @@ func (self AggressiveAi1) Orders(logger common.Logger, me state.PlayerId, s *sta
         }
     }
 
+    // drop or fix any invalid orders
+    result, changes := common.NormalizeOrders(me, s, result)
+    for _, change := range changes {
+        logger.Printf("NormalizeOrders %v", change)
+    }
+
     // all done, return the orders list
     return
 }
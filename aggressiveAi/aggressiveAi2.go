@@ -0,0 +1,50 @@
+package aggressiveAi
+
+import (
+    common "github.com/miridius/ai/common"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    state "github.com/zond/stockholm-ai/state"
+)
+
+/*
+Aggressive AI v2
+Same algorithm as AggressiveAi1, with both of its ideas for improvements:
+ - enemy units on edges count as belonging to the node they are going to land on, so unclaimed nodes with
+   enemies on the way are attacked, and friendly nodes that can't hold on their own get sent reinforcements
+ - each node only keeps as many defenders as it needs to kill all enemy soldiers heading to it
+   (at least 1), the rest are available to send into battle elsewhere
+*/
+type AggressiveAi2 struct{}
+
+// v2 defence: count enemies on their way to a node and keep enough defenders to beat them
+type countedDefence struct {
+    me     state.PlayerId
+    counts map[state.NodeId]*common.UnitCounts
+}
+
+// incoming enemies only count against nodes I hold if the garrison can't deal with them, so that they get reinforcements
+func (self countedDefence) enemyUnits(node *state.Node) int {
+    if units := node.Units[self.me]; units > 0 && self.defenders(node, units) < units {
+        return 0
+    }
+    return self.counts[node.Id].EnemyUnits
+}
+
+func (self countedDefence) defenders(node *state.Node, units int) int {
+    if self.counts[node.Id].EnemyUnits == 0 || units <= 1 {
+        return 1
+    }
+    // the smallest garrison that holds, or if everyone together can't, all of them to kill as many enemies as possible
+    return common.Max(1, common.Min(common.UnitsToHold(self.me, node, self.counts[node.Id].Arrivals, units), units))
+}
+
+/*
+Orders will analyze all nodes in s and return orders for each one
+*/
+func (self AggressiveAi2) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) state.Orders {
+    logger.Printf("AggressiveAi2 calculating orders for player: %v", me)
+    return aggressiveOrders(logger, me, s, countedDefence{
+        me:     me,
+        counts: common.CountAllUnits(me, s),
+    })
+}
@@ -1,9 +1,13 @@
 package aggressiveAi
 
 import (
+    "github.com/miridius/ai/aitest"
+    common "github.com/miridius/ai/common"
     "github.com/zond/stockholm-ai/state"
+    "io/ioutil"
     "log"
     "os"
+    "reflect"
     "testing"
 )
 
@@ -37,3 +41,142 @@ func TestOrders(t *testing.T) {
     //print winner
     logger.Printf("onlyPlayerLeft: %v", *onlyPlayerLeft)
 }
+
+func TestOrdersDeterministic(t *testing.T) {
+    // the same state must always give the same orders
+    aitest.CheckDeterministic(t, 50, AggressiveAi1{}, AggressiveAi2{}, OptimalAggressiveAi{})
+}
+
+func TestOptimalOrders(t *testing.T) {
+    // v1 against the optimal version on a fixed set of maps, the optimal version must win more games
+    wins := aitest.Series(t, 30, 500, map[state.PlayerId]aitest.AI{
+        "v1":      AggressiveAi1{},
+        "optimal": OptimalAggressiveAi{},
+    })
+    if wins["optimal"] <= wins["v1"] {
+        t.Errorf("expected the optimal version to win more games than v1, got %v", wins)
+    }
+}
+
+func TestOptimalCapture(t *testing.T) {
+    // home - unclaimed is 1 turn, home - enemy is 2 turns and enemy holds 3 units
+    s := aitest.NewState(50, "home", "unclaimed", "enemy")
+    aitest.Connect(s, "home", "unclaimed", 1)
+    aitest.Connect(s, "home", "enemy", 2)
+    s.Nodes["home"].Units["me"] = 10
+    s.Nodes["enemy"].Units["them"] = 3
+
+    // 1 unit claims the unclaimed node, everything else but the one left home attacks
+    orders := OptimalAggressiveAi{}.Orders(log.New(ioutil.Discard, "", 0), "me", s)
+    expected := map[state.NodeId]int{"unclaimed": 1, "enemy": 8}
+    sent := make(map[state.NodeId]int)
+    for _, order := range orders {
+        if order.Src != "home" {
+            t.Errorf("expected orders from home only, got %v", order)
+        }
+        sent[order.Dst] += order.Units
+    }
+    if !reflect.DeepEqual(sent, expected) {
+        t.Errorf("expected to send %v, got %v", expected, orders)
+    }
+
+    // 4 of my units land on enemy in 1 turn, not enough against 5 so 2 more follow, the rest attacks the closer near
+    s = aitest.NewState(50, "home", "near", "enemy")
+    aitest.Connect(s, "home", "near", 1)
+    aitest.Connect(s, "home", "enemy", 3)
+    s.Nodes["home"].Units["me"] = 10
+    s.Nodes["near"].Units["them"] = 2
+    s.Nodes["enemy"].Units["them"] = 5
+    s.Nodes["home"].Edges["enemy"].Units[2] = state.Units{"me": 4}
+    orders = OptimalAggressiveAi{}.Orders(log.New(ioutil.Discard, "", 0), "me", s)
+    expected = map[state.NodeId]int{"near": 7, "enemy": 2}
+    sent = make(map[state.NodeId]int)
+    for _, order := range orders {
+        sent[order.Dst] += order.Units
+    }
+    if !reflect.DeepEqual(sent, expected) {
+        t.Errorf("expected to send %v, got %v", expected, orders)
+    }
+
+    // a has 2 soldiers to spare next to unclaimed and enemy (needing 2), b has 1 soldier 5 turns from unclaimed.
+    // claiming first would send 1 from a to unclaimed and attack with the other and the one from b, 7 turns away
+    s = aitest.NewState(50, "a", "b", "unclaimed", "enemy")
+    aitest.Connect(s, "a", "unclaimed", 1)
+    aitest.Connect(s, "a", "enemy", 1)
+    aitest.Connect(s, "b", "unclaimed", 5)
+    s.Nodes["a"].Units["me"] = 3
+    s.Nodes["b"].Units["me"] = 2
+    s.Nodes["enemy"].Units["them"] = 1
+    orders = OptimalAggressiveAi{}.Orders(log.New(ioutil.Discard, "", 0), "me", s)
+    expected = map[state.NodeId]int{"a-enemy": 2, "b-unclaimed": 1}
+    sent = make(map[state.NodeId]int)
+    for _, order := range orders {
+        sent[order.Src+"-"+order.Dst] += order.Units
+    }
+    if !reflect.DeepEqual(sent, expected) {
+        t.Errorf("expected to send %v, got %v", expected, orders)
+    }
+}
+
+func TestDefenders(t *testing.T) {
+    // home (30 of my units) is threatened by 10 enemy units landing in 3 turns
+    s := aitest.ThreatenedState(0, 30, 10)
+    defence := countedDefence{me: "me", counts: common.CountAllUnits("me", s)}
+    home := s.Nodes["home"]
+    if units := defence.enemyUnits(s.Nodes["unclaimed"]); units != 0 {
+        t.Errorf("expected no enemies on target, got %v", units)
+    }
+    keep := defence.defenders(home, 30)
+    if keep <= 1 || keep >= 30 {
+        t.Fatalf("expected more than 1 but not all defenders, got %v", keep)
+    }
+    if hold := common.UnitsToHold("me", home, common.Timeline{3: {"them": 10}}, 30); keep != hold {
+        t.Errorf("expected the smallest garrison that holds home (%v), got %v", hold, keep)
+    }
+    if keep := defence.defenders(s.Nodes["unclaimed"], 0); keep != 1 {
+        t.Errorf("expected 1 defender on a node without threats, got %v", keep)
+    }
+
+    // home can hold on its own, so it doesn't count as an enemy node
+    if units := defence.enemyUnits(home); units != 0 {
+        t.Errorf("expected the garrison to deal with the incoming enemies, got %v enemy units", units)
+    }
+    // unless we only have a single guy there
+    home.Units["me"] = 1
+    if units := defence.enemyUnits(home); units != 10 {
+        t.Errorf("expected the 10 incoming enemies to count towards home, got %v", units)
+    }
+    home.Units["me"] = 30
+
+    // v1 only leaves 1 guy home, v2 keeps the garrison
+    logger := log.New(ioutil.Discard, "", 0)
+    sent := 0
+    for _, order := range (AggressiveAi1{}).Orders(logger, "me", s) {
+        if order.Src == "home" {
+            sent += order.Units
+        }
+    }
+    if sent != 29 {
+        t.Errorf("expected v1 to send 29 units from home, sent %v", sent)
+    }
+    sent = 0
+    for _, order := range (AggressiveAi2{}).Orders(logger, "me", s) {
+        if order.Src == "home" {
+            sent += order.Units
+        }
+    }
+    if sent != 30-keep {
+        t.Errorf("expected v2 to send %v units from home, sent %v", 30-keep, sent)
+    }
+}
+
+func TestV2Orders(t *testing.T) {
+    // v1 against v2 on a fixed set of maps, v2 must win more games
+    wins := aitest.Series(t, 30, 500, map[state.PlayerId]aitest.AI{
+        "v1": AggressiveAi1{},
+        "v2": AggressiveAi2{},
+    })
+    if wins["v2"] <= wins["v1"] {
+        t.Errorf("expected v2 to win more games than v1, got %v", wins)
+    }
+}
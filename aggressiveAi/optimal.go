@@ -0,0 +1,132 @@
+package aggressiveAi
+
+import (
+    common "github.com/miridius/ai/common"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    state "github.com/zond/stockholm-ai/state"
+)
+
+/*
+Optimal Aggressive AI
+Same idea as AggressiveAi1, but soldiers are matched to targets as a whole instead of one by one.
+
+v1 Algorithm:
+0. Calculate available soldiers = every soldier on the map (including edges) excluding one guy left to defend on each friendly node
+1. Solve the assignment of available soldiers to unclaimed nodes (1 soldier each) and enemy nodes (enough soldiers to beat
+   what is there and on its way) together, as one min cost flow minimising total arrival time
+2. Send any remaining available soldiers towards closest enemy node (or friendly nodes with enemy units)
+*/
+type OptimalAggressiveAi struct{}
+
+// where and when some available soldiers will be ready for orders
+type packet struct {
+    node  state.NodeId
+    delay int
+}
+
+/*
+Orders will analyze all nodes in s and return orders for each one
+*/
+func (self OptimalAggressiveAi) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) (result state.Orders) {
+
+    logger.Printf("OptimalAggressiveAi calculating orders for player: %v", me)
+
+    distances := common.GetTopology(s).Distances
+    unitCounts := common.CountAllUnits(me, s)
+    nodeIds := common.SortedNodeIds(s)
+
+    // 0. find all available soldiers (by where and when they are available) and all targets
+    available := make(map[packet]int, len(s.Nodes))
+    packets := make([]packet, 0, len(s.Nodes))
+    addAvailable := func(p packet, units int) {
+        if _, found := available[p]; !found {
+            packets = append(packets, p)
+        }
+        available[p] += units
+    }
+    unclaimed := make([]common.Demand, 0, len(s.Nodes))
+    enemy := make([]common.Demand, 0, len(s.Nodes))
+    for _, nodeId := range nodeIds {
+        node := s.Nodes[nodeId]
+        counts := unitCounts[nodeId]
+        units := node.Units[me]
+        // always leave 1 home to keep ownership of the node
+        if units > 1 {
+            addAvailable(packet{nodeId, 0}, units-1)
+        }
+        for _, edge := range common.SortedEdges(node) {
+            for index, unitMap := range edge.Units {
+                if numUnits := unitMap[me]; numUnits > 0 {
+                    addAvailable(packet{edge.Dst, len(edge.Units) - index}, numUnits)
+                }
+            }
+        }
+        if counts.EnemyUnits > 0 {
+            // enough to kill everything that is there or on its way. our own units there or on their way are
+            // offered as supplies above, and the solver sends them first, so only the one left home counts here
+            needed := counts.EnemyUnits + 1 - common.Min(common.Max(units, 0), 1)
+            enemy = append(enemy, common.Demand{Node: nodeId, Units: needed})
+        } else if units <= 0 {
+            unclaimed = append(unclaimed, common.Demand{Node: nodeId, Units: 1})
+        }
+    }
+    logger.Printf("Unclaimed: %v  Enemy: %v  Packets: %v", len(unclaimed), len(enemy), len(packets))
+
+    // list everything still available as supplies for the flow solver
+    supplies := func() (result []common.Supply) {
+        for _, p := range packets {
+            if units := available[p]; units > 0 {
+                result = append(result, common.Supply{Node: p.node, Delay: p.delay, Units: units})
+            }
+        }
+        return
+    }
+    // send the soldiers of each assignment on their way, and mark them as unavailable
+    send := func(assignments []common.Assignment) {
+        for _, assignment := range assignments {
+            available[packet{assignment.Supply.Node, assignment.Supply.Delay}] -= assignment.Units
+            // soldiers on edges can't be given orders until they land, but they are still taken
+            if assignment.Supply.Delay == 0 && assignment.Target != assignment.Supply.Node {
+                result = append(result, state.Order{
+                    Src:   assignment.Supply.Node,
+                    Dst:   distances.NextHop(assignment.Supply.Node, assignment.Target),
+                    Units: assignment.Units,
+                })
+            }
+        }
+    }
+
+    // 1. claim unclaimed nodes and attack enemy nodes, so a soldier only claims a node if nobody else can do it cheaper
+    send(common.AllocateSoldiers(supplies(), append(unclaimed, enemy...), distances))
+
+    // 2. send remaining available units to closest node that has any enemy units on it
+    for _, supply := range supplies() {
+        if supply.Delay > 0 {
+            continue
+        }
+        best := -1
+        bestEdge := supply.Node
+        for _, target := range enemy {
+            if dist := distances.Distance(supply.Node, target.Node); best < 0 || dist < best {
+                best = dist
+                bestEdge = distances.NextHop(supply.Node, target.Node)
+            }
+        }
+        if bestEdge != supply.Node {
+            result = append(result, state.Order{
+                Src:   supply.Node,
+                Dst:   bestEdge,
+                Units: supply.Units,
+            })
+        }
+    }
+
+    // drop or fix any invalid orders
+    result, changes := common.NormalizeOrders(me, s, result)
+    for _, change := range changes {
+        logger.Printf("NormalizeOrders %v", change)
+    }
+
+    // all done, return the orders list
+    return
+}
@@ -0,0 +1,170 @@
+// aitest by Miridius
+// Helpers shared by the tests of all AIs: hand-built and seeded maps, and checks that play whole games
+package aitest
+
+import (
+    "fmt"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    "github.com/zond/stockholm-ai/state"
+    "io/ioutil"
+    "log"
+    "math/rand"
+    "reflect"
+    "sort"
+    "testing"
+)
+
+// AI is anything that gives orders, like all the AIs in this repo
+type AI interface {
+    Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) state.Orders
+}
+
+// logger for games played by tests, nobody wants to read that output
+var quietLogger = log.New(ioutil.Discard, "", 0)
+
+// adds an empty node to s
+func addNode(s *state.State, id state.NodeId, size int) {
+    s.Nodes[id] = &state.Node{
+        Id:    id,
+        Size:  size,
+        Units: make(map[state.PlayerId]int),
+        Edges: make(map[state.NodeId]state.Edge),
+    }
+}
+
+// NewState builds a map of nodes of the given size, without any edges or units
+func NewState(size int, ids ...state.NodeId) *state.State {
+    s := &state.State{Nodes: make(map[state.NodeId]*state.Node)}
+    for _, id := range ids {
+        addNode(s, id, size)
+    }
+    return s
+}
+
+// Connect adds edges in both directions between src and dst
+func Connect(s *state.State, src, dst state.NodeId, length int) {
+    s.Nodes[src].Edges[dst] = state.Edge{Src: src, Dst: dst, Units: make([]state.Units, length)}
+    s.Nodes[dst].Edges[src] = state.Edge{Src: dst, Dst: src, Units: make([]state.Units, length)}
+}
+
+// Send puts units of player on the edge from src to dst, as if ordered there this turn
+func Send(s *state.State, src, dst state.NodeId, player state.PlayerId, units int) {
+    s.Nodes[src].Edges[dst].Units[0] = state.Units{player: units}
+}
+
+/*
+ThreatenedState builds the line interior - home - enemy (4 and 3 turns long) with an unclaimed node hanging off
+home (1 turn), all of size 60. "me" has interior and home units on those nodes, "them" holds enemy with 10 units
+and has attackers on their way to home, landing in 3 turns.
+*/
+func ThreatenedState(interior, home, attackers int) *state.State {
+    s := NewState(60, "interior", "home", "enemy", "unclaimed")
+    Connect(s, "interior", "home", 4)
+    Connect(s, "home", "enemy", 3)
+    Connect(s, "home", "unclaimed", 1)
+    s.Nodes["interior"].Units["me"] = interior
+    s.Nodes["home"].Units["me"] = home
+    s.Nodes["enemy"].Units["them"] = 10
+    if attackers > 0 {
+        Send(s, "enemy", "home", "them", attackers)
+    }
+    return s
+}
+
+// SeededState builds a random map like state.RandomState, but the same one every time for the same seed
+func SeededState(seed int64, players []state.PlayerId) *state.State {
+    random := rand.New(rand.NewSource(seed))
+    n := 10 + random.Intn(10)
+    ids := []state.NodeId{}
+    s := NewState(0)
+    for i := 0; i < n; i++ {
+        id := state.NodeId(fmt.Sprintf("n%d", i))
+        ids = append(ids, id)
+        addNode(s, id, 10+random.Intn(90))
+    }
+    for i := 1; i < n; i++ {
+        Connect(s, ids[i], ids[random.Intn(i)], 1+random.Intn(4))
+    }
+    for i := 0; i < n/2; i++ {
+        a, b := ids[random.Intn(n)], ids[random.Intn(n)]
+        if _, found := s.Nodes[a].Edges[b]; a != b && !found {
+            Connect(s, a, b, 1+random.Intn(4))
+        }
+    }
+    perm := random.Perm(n)
+    for i, player := range players {
+        s.Nodes[ids[perm[i]]].Units[player] = 10
+    }
+    return s
+}
+
+/*
+CheckDeterministic plays turns turns of a four player game with each AI on a seeded map, and fails t if asking
+for orders again for the same state ever gives different orders
+*/
+func CheckDeterministic(t *testing.T, turns int, ais ...AI) {
+    players := []state.PlayerId{"a", "b", "c", "d"}
+    for index, ai := range ais {
+        orderMap := make(map[state.PlayerId]state.Orders, len(players))
+        s := SeededState(int64(index), players)
+        for turn := 0; turn < turns; turn++ {
+            for _, player := range players {
+                orderMap[player] = ai.Orders(quietLogger, player, s)
+                for i := 0; i < 5; i++ {
+                    if again := ai.Orders(quietLogger, player, s); !reflect.DeepEqual(again, orderMap[player]) {
+                        t.Fatalf("%T, turn %v, player %v: got %v and then %v", ai, turn, player, orderMap[player], again)
+                    }
+                }
+            }
+            if onlyPlayerLeft := s.Next(quietLogger, orderMap); onlyPlayerLeft != nil {
+                break
+            }
+        }
+    }
+}
+
+// define a sortable list of player ids
+type playerIds []state.PlayerId
+
+func (s playerIds) Len() int           { return len(s) }
+func (s playerIds) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s playerIds) Less(i, j int) bool { return s[i] < s[j] }
+
+/*
+Series plays a game between ais on the SeededState maps 0 to maps-1, once with every player on every starting spot,
+and returns how many games each player won. Games still going after maxTurns don't count for anyone.
+*/
+func Series(t *testing.T, maps, maxTurns int, ais map[state.PlayerId]AI) (wins map[state.PlayerId]int) {
+    players := playerIds{}
+    for player := range ais {
+        players = append(players, player)
+    }
+    sort.Sort(players)
+
+    wins = make(map[state.PlayerId]int)
+    for game := 0; game < maps*len(players); game++ {
+        // rotate the starting spots
+        spots := make([]state.PlayerId, len(players))
+        for i := range players {
+            spots[i] = players[(i+game)%len(players)]
+        }
+        s := SeededState(int64(game/len(players)), spots)
+        orderMap := make(map[state.PlayerId]state.Orders, len(players))
+        var onlyPlayerLeft *state.PlayerId
+        turn := 0
+        for ; turn < maxTurns && onlyPlayerLeft == nil; turn++ {
+            for _, player := range players {
+                orderMap[player] = ais[player].Orders(quietLogger, player, s)
+            }
+            onlyPlayerLeft = s.Next(quietLogger, orderMap)
+        }
+        if onlyPlayerLeft != nil {
+            wins[*onlyPlayerLeft]++
+            t.Logf("game %v: %v won after %v turns", game, *onlyPlayerLeft, turn)
+        } else {
+            t.Logf("game %v: no winner after %v turns", game, turn)
+        }
+    }
+    t.Logf("wins: %v", wins)
+    return
+}
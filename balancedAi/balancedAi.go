@@ -2,7 +2,8 @@
 package balancedAi
 
 import (
-    common "github.com/zond/stockholm-ai/common"
+    common "github.com/miridius/ai/common"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
     state "github.com/zond/stockholm-ai/state"
 )
 
@@ -13,60 +14,93 @@ v1 algorithm:
 1. For each node i in s:
     a. i.Attraction = how much growth I would gain by sending 1 soldier there
 2. For each node j in s where I have units:
-    a. For each node k, attraction to k = k.Attraction / (distance from j to k)
+    a. For each node k, attraction to k = k.Attraction / (distance in turns from j to k, see common.DistanceMatrix)
+       (this used to divide by the number of hops on the path, so nodes behind long edges now attract fewer units)
     b. attraction of each edge connected to j is the sum of the attractions of nodes whos path start with that edge
     c. attraction of not moving = j.Attraction
     d. leave 1 unit to hold the node, and divide remaining units amongst all edges proportionally based on attraction ratios
 
 Known issues:
 1. Soldiers currently on edges are not considered in calculations, which causes the AI to send out units more often than really necessary.
-2. Playing multiple balanced AIs against each other can result in deadlock
+2. Playing multiple balanced AIs against each other can result in deadlock (fixed by deadlock breaking, see deadlock.go)
 */
 type BalancedAi1 struct{}
 
+// number of turns to look ahead when valuing growth
+const GROWTH_HORIZON = 10
+
 /*
 Orders will analyze all nodes in s and return orders for each one
 */
-func (self BalancedAi1) Orders(logger common.Logger, me state.PlayerId, s *state.State) (result state.Orders) {
+func (self BalancedAi1) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) state.Orders {
 
     logger.Printf("BalancedAi1 calculating orders for player: %v", me)
 
-    var attraction, totalAttraction float64
-    var edge state.NodeId
+    // commit to an attack if we are stuck
+    if orders, attacking := breakDeadlock(logger, "v1", me, s); attacking {
+        return orders
+    }
+
+    var attraction float64
+    // always visit nodes in the same order so the same state gives the same orders
+    nodeIds := common.SortedNodeIds(s)
     // Calculate base attraction for all nodes
     attractions := make(map[state.NodeId]float64, len(s.Nodes)+1)
-    for _, node := range s.Nodes {
+    for _, nodeId := range nodeIds {
+        node := s.Nodes[nodeId]
         if node.Units[me] < 1 {
             attraction = 1
         } else {
             attraction = 0
         }
-        attraction = attraction + (0.2 * float64(node.Units[me]) / float64(node.Size))
+        // extra growth per turn from sending 1 more soldier there
+        attraction = attraction + float64(common.MarginalGrowth(node.Units[me], node.Size, GROWTH_HORIZON))/GROWTH_HORIZON
 
         attractions[node.Id] = attraction
     }
+    return distribute(logger, me, s, attractions)
+}
+
+/*
+distribute divides the units on each of my nodes (after leaving 1 behind to defend) amongst its edges,
+proportionally to the attraction of the nodes whose shortest path starts with that edge
+*/
+func distribute(logger stockholmCommon.Logger, me state.PlayerId, s *state.State, attractions map[state.NodeId]float64) (result state.Orders) {
+    var attraction, totalAttraction float64
+    var edge state.NodeId
+    distances := common.GetTopology(s).Distances
+    nodeIds := common.SortedNodeIds(s)
 
     // For each node in s
-    for _, node := range s.Nodes {
+    for _, nodeId := range nodeIds {
+        node := s.Nodes[nodeId]
         // If I have units there (after leaving 1 behind to defend)
         if units := node.Units[me] - 1; units > 0 {
             // Check my attraction to all other nodes and keep an attraction sum for each starting edge.
             edgeAttractions := make(map[state.NodeId]float64, len(node.Edges)+1)
             totalAttraction = 0
-            for _, destNode := range s.Nodes {
-                path := s.Path(node.Id, destNode.Id, nil)
-                if len(path) > 0 {
-                    edge = path[0]
-                    attraction = attractions[destNode.Id] / float64(len(path))
+            for _, destId := range nodeIds {
+                if edge = distances.NextHop(node.Id, destId); edge != node.Id {
+                    attraction = attractions[destId] / float64(distances.Distance(node.Id, destId))
                 } else {
                     edge = node.Id
-                    attraction = attractions[destNode.Id]
+                    attraction = attractions[destId]
                 }
                 edgeAttractions[edge] = edgeAttractions[edge] + attraction
                 totalAttraction = totalAttraction + attraction
             }
-            // go through all edges and send units accordingly
-            for edgeId, att := range edgeAttractions {
+            // go through all edges and send units accordingly, staying home last so it gets any rounding remainder
+            edgeIds := make([]state.NodeId, 0, len(edgeAttractions))
+            for _, e := range common.SortedEdges(node) {
+                if _, found := edgeAttractions[e.Dst]; found {
+                    edgeIds = append(edgeIds, e.Dst)
+                }
+            }
+            if _, found := edgeAttractions[node.Id]; found {
+                edgeIds = append(edgeIds, node.Id)
+            }
+            for _, edgeId := range edgeIds {
+                att := edgeAttractions[edgeId]
                 // in case of rounding errors or some other hiccup, make sure current edge's attraction <= total
                 if att > totalAttraction {
                     totalAttraction = att
@@ -82,5 +116,10 @@ func (self BalancedAi1) Orders(logger common.Logger, me state.PlayerId, s *state
             }
         }
     }
+    // drop or fix any invalid orders
+    result, changes := common.NormalizeOrders(me, s, result)
+    for _, change := range changes {
+        logger.Printf("NormalizeOrders %v", change)
+    }
     return
 }
@@ -0,0 +1,67 @@
+package balancedAi
+
+import (
+    common "github.com/miridius/ai/common"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    state "github.com/zond/stockholm-ai/state"
+)
+
+/*
+Balanced AI v2
+Fixes known issue 1 of v1 by counting soldiers on edges as belonging to the node they land on.
+v2 algorithm:
+1. For each node i in s, only looking at soldiers landing within GROWTH_HORIZON turns:
+    a. expected = my units on i + my units heading to i
+    b. if enemies are heading to i, i.Attraction = 1 + the number of soldiers needed to beat them / i.Size, at most 2
+       (only counting my soldiers that land no later than them), so however big the attack a threatened node weighs
+       like an unclaimed one plus how much of it is at stake
+    c. otherwise i.Attraction = 1 if expected < 1 (nothing of mine there or on the way)
+    d. plus how much growth I would gain by sending 1 more soldier there, on top of the expected units
+2. Distribute units along edges exactly like v1
+*/
+type BalancedAi2 struct{}
+
+// attraction2 calculates the v2 attraction of node, given the units on their way to it
+func attraction2(me state.PlayerId, node *state.Node, arrivals common.Timeline) (attraction float64) {
+    friendly := node.Units[me]
+    enemy := 0
+    needed := 0
+    for _, turn := range arrivals.Turns() {
+        if turn > GROWTH_HORIZON {
+            break
+        }
+        friendly += arrivals.Friendly(me, turn)
+        enemy += arrivals.Enemy(me, turn)
+        if enemy > 0 {
+            needed = common.Max(needed, enemy-friendly+1)
+        }
+    }
+    if needed > 0 {
+        attraction = 1 + float64(common.Min(needed, node.Size))/float64(node.Size)
+    } else if friendly < 1 {
+        attraction = 1
+    }
+    // extra growth per turn from sending 1 more soldier there
+    expected := common.Max(friendly-enemy, 0)
+    return attraction + float64(common.MarginalGrowth(expected, node.Size, GROWTH_HORIZON))/GROWTH_HORIZON
+}
+
+/*
+Orders will analyze all nodes in s and return orders for each one
+*/
+func (self BalancedAi2) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) state.Orders {
+
+    logger.Printf("BalancedAi2 calculating orders for player: %v", me)
+
+    // commit to an attack if we are stuck
+    if orders, attacking := breakDeadlock(logger, "v2", me, s); attacking {
+        return orders
+    }
+
+    counts := common.CountAllUnits(me, s)
+    attractions := make(map[state.NodeId]float64, len(s.Nodes)+1)
+    for _, nodeId := range common.SortedNodeIds(s) {
+        attractions[nodeId] = attraction2(me, s.Nodes[nodeId], counts[nodeId].Arrivals)
+    }
+    return distribute(logger, me, s, attractions)
+}
@@ -1,7 +1,10 @@
 package balancedAi
 
 import (
+    "github.com/miridius/ai/aitest"
+    common "github.com/miridius/ai/common"
     "github.com/zond/stockholm-ai/state"
+    "io/ioutil"
     "log"
     "os"
     "testing"
@@ -37,3 +40,140 @@ func TestOrders(t *testing.T) {
     //print winner
     logger.Printf("onlyPlayerLeft: %v", *onlyPlayerLeft)
 }
+
+func TestOrdersDeterministic(t *testing.T) {
+    // the same state must always give the same orders
+    aitest.CheckDeterministic(t, 50, BalancedAi1{}, BalancedAi2{})
+}
+
+// a star around home, where 5 of my soldiers are already on their way to b but nobody is going to c
+func helpOnTheWayState() *state.State {
+    s := aitest.NewState(20, "home", "b", "c")
+    aitest.Connect(s, "home", "b", 2)
+    aitest.Connect(s, "home", "c", 2)
+    s.Nodes["home"].Size = 40
+    s.Nodes["home"].Units["me"] = 30
+    aitest.Send(s, "home", "b", "me", 5)
+    return s
+}
+
+// sums the units sent to each destination
+func sent(orders state.Orders) (result map[state.NodeId]int) {
+    result = make(map[state.NodeId]int)
+    for _, order := range orders {
+        result[order.Dst] += order.Units
+    }
+    return
+}
+
+func TestOrdersCountEdges(t *testing.T) {
+    logger := log.New(ioutil.Discard, "", 0)
+    s := helpOnTheWayState()
+    v1 := sent(BalancedAi1{}.Orders(logger, "me", s))
+    v2 := sent(BalancedAi2{}.Orders(logger, "me", s))
+    t.Logf("v1 sends %v, v2 sends %v", v1, v2)
+    if v1["b"] < v1["c"] {
+        t.Errorf("expected v1 to send at least as many units to b as to c, got %v", v1)
+    }
+    if v2["b"] >= v1["b"] {
+        t.Errorf("expected v2 to send fewer units than v1 to b, which already has help on the way: v1 %v, v2 %v", v1["b"], v2["b"])
+    }
+    if v2["b"] >= v2["c"] {
+        t.Errorf("expected v2 to send fewer units to b than to c, got %v", v2)
+    }
+
+    // enemies heading to b make it attractive again
+    s.Nodes["c"].Units["them"] = 10
+    aitest.Connect(s, "c", "b", 2)
+    s.Nodes["c"].Edges["b"].Units[1] = state.Units{"them": 20}
+    if threatened := sent(BalancedAi2{}.Orders(logger, "me", s)); threatened["b"] <= v2["b"] {
+        t.Errorf("expected v2 to send more units to b once enemies are heading there, got %v before and %v after", v2["b"], threatened["b"])
+    }
+}
+
+func TestAttraction2(t *testing.T) {
+    s := helpOnTheWayState()
+    attraction := func(nodeId state.NodeId) float64 {
+        return attraction2("me", s.Nodes[nodeId], common.CountAllUnits("me", s)[nodeId].Arrivals)
+    }
+    // unclaimed c is worth 1 plus the growth of a first soldier there
+    if a := attraction("c"); a <= 1 {
+        t.Errorf("expected c to attract more than 1, got %v", a)
+    }
+    // b is threatened by more than its size, which counts as much as losing all of it
+    s.Nodes["c"].Units["them"] = 10
+    aitest.Connect(s, "c", "b", 2)
+    s.Nodes["c"].Edges["b"].Units[0] = state.Units{"them": 1000}
+    if a := attraction("b"); a < 2 || a > attraction("c")+1 {
+        t.Errorf("expected a huge attack on b to weigh no more than an unclaimed node plus 1, got %v", a)
+    }
+}
+
+func TestDeadlockAttack(t *testing.T) {
+    s := aitest.NewState(20, "home", "target", "island")
+    aitest.Connect(s, "home", "target", 2)
+    s.Nodes["home"].Units["me"] = 10
+    s.Nodes["target"].Units["them"] = 30
+    s.Nodes["island"].Units["them"] = 1
+    attack := func(target state.NodeId) bool {
+        breaker := &deadlockBreaker{target: target, owner: "them"}
+        orders, ok := breaker.attack("me", s, common.GetTopology(s).Distances)
+        if len(orders) > 0 {
+            t.Errorf("expected to wait for more units, got %v", orders)
+        }
+        return ok
+    }
+    // nothing of mine can reach the island
+    if attack("island") {
+        t.Errorf("expected the attack on the island not to go ahead")
+    }
+    // home is still growing towards enough to take the target
+    if !attack("target") {
+        t.Errorf("expected to wait for home to grow")
+    }
+    // but once it is full that will never happen
+    s.Nodes["home"].Units["me"] = 20
+    if attack("target") {
+        t.Errorf("expected the attack not to go ahead from a full node")
+    }
+}
+
+func TestMirrorMatch(t *testing.T) {
+    // identical balanced AIs used to be able to deadlock forever, on these maps they reach a stalemate
+    // that the deadlock breaker has to end
+    logger := log.New(ioutil.Discard, "", 0)
+    players := []state.PlayerId{"a", "b", "c", "d"}
+    for _, mirror := range []struct {
+        version string
+        ai      aitest.AI
+        seeds   []int64
+    }{
+        {"v1", BalancedAi1{}, []int64{1, 3, 6, 10}},
+        {"v2", BalancedAi2{}, []int64{6, 20, 28, 29}},
+    } {
+        for _, seed := range mirror.seeds {
+            s := aitest.SeededState(seed, players)
+            orderMap := make(map[state.PlayerId]state.Orders, len(players))
+            var onlyPlayerLeft *state.PlayerId
+            engaged := false
+            turn := 0
+            for ; turn < 1000 && onlyPlayerLeft == nil; turn++ {
+                for _, player := range players {
+                    orderMap[player] = mirror.ai.Orders(logger, player, s)
+                    if getDeadlockBreaker(mirror.version, player, s).target != "" {
+                        engaged = true
+                    }
+                }
+                onlyPlayerLeft = s.Next(logger, orderMap)
+            }
+            if !engaged {
+                t.Errorf("%v map %v: expected the deadlock breaker to attack", mirror.version, seed)
+            }
+            if onlyPlayerLeft == nil {
+                t.Errorf("%v map %v: no winner after %v turns", mirror.version, seed, turn)
+                continue
+            }
+            t.Logf("%v map %v: %v won after %v turns", mirror.version, seed, *onlyPlayerLeft, turn)
+        }
+    }
+}
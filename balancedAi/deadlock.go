@@ -0,0 +1,203 @@
+package balancedAi
+
+import (
+    common "github.com/miridius/ai/common"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    state "github.com/zond/stockholm-ai/state"
+    "sync"
+)
+
+// how many turns an attack may take before we give up on it and go back to balancing
+const ATTACK_TURNS = 60
+
+/*
+Deadlock breaking (known issue 2)
+Balanced AIs keep spreading their units out, so several of them can end up trickling soldiers into each other's
+borders forever without anything changing. Every turn a StalemateDetector (one per game and player, remembered
+with common.GameOf) looks at the state, and once nothing has happened for common.STALEMATE_TURNS turns:
+1. Pick the weakest neighbour: the opponent with the fewest units that can reach one of my nodes within common.NEIGHBOUR_TURNS
+2. Pick its weakest node as the target: fewest units, then closest to my nodes
+3. Pick my node closest to the target as the staging node
+4. Every turn until the target changes owner (or ATTACK_TURNS have passed):
+    a. all my other nodes send their units (leaving 1 to hold the node) to the staging node
+    b. the staging node keeps everything until it has enough to take the target, and then sends it all
+Once the attack is over we go back to balancing. We also give up (and balance) as soon as the attack can't go ahead:
+no node of mine can reach the target any more, or the staging node is waiting for units that will never come.
+*/
+type deadlockBreaker struct {
+    mutex    sync.Mutex
+    detector *common.StalemateDetector
+    // the current attack, if any
+    target  state.NodeId
+    owner   state.PlayerId // owner of the target when the attack started
+    started int
+}
+
+// the deadlock breaker of me in the game that s belongs to
+func getDeadlockBreaker(version string, me state.PlayerId, s *state.State) *deadlockBreaker {
+    key := "balancedAi/" + version + "/deadlock/" + string(me)
+    return common.GameOf(s).Value(key, func() interface{} {
+        return &deadlockBreaker{detector: common.NewStalemateDetector(common.STALEMATE_TURNS)}
+    }).(*deadlockBreaker)
+}
+
+// the only player with units on node, or "" if none
+func owner(node *state.Node) (result state.PlayerId) {
+    for player, numUnits := range node.Units {
+        if numUnits > 0 {
+            result = player
+        }
+    }
+    return
+}
+
+// how far each node is from the nearest node I hold
+func reach(me state.PlayerId, s *state.State, distances *common.DistanceMatrix) (result map[state.NodeId]int) {
+    nodeIds := common.SortedNodeIds(s)
+    result = make(map[state.NodeId]int, len(s.Nodes))
+    for _, nodeId := range nodeIds {
+        result[nodeId] = common.UNREACHABLE
+        for _, src := range nodeIds {
+            if s.Nodes[src].Units[me] > 0 {
+                result[nodeId] = common.Min(result[nodeId], distances.Distance(src, nodeId))
+            }
+        }
+    }
+    return
+}
+
+// finds the weakest node of my weakest neighbour, returns false if no opponent is in reach
+func weakestNeighbour(me state.PlayerId, s *state.State, distances *common.DistanceMatrix) (target state.NodeId, found bool) {
+    nodeIds := common.SortedNodeIds(s)
+    turns := reach(me, s, distances)
+    weakestUnits := -1
+    var weakest state.PlayerId
+    for _, standing := range common.Standings(s) {
+        if standing.Player == me || (weakestUnits >= 0 && standing.Units() >= weakestUnits) {
+            continue
+        }
+        for _, nodeId := range nodeIds {
+            if s.Nodes[nodeId].Units[standing.Player] > 0 && turns[nodeId] <= common.NEIGHBOUR_TURNS {
+                weakest = standing.Player
+                weakestUnits = standing.Units()
+                break
+            }
+        }
+    }
+    if weakestUnits < 0 {
+        return
+    }
+    best := -1
+    for _, nodeId := range nodeIds {
+        units := s.Nodes[nodeId].Units[weakest]
+        if units <= 0 || turns[nodeId] == common.UNREACHABLE {
+            continue
+        }
+        if best < 0 || units < best || (units == best && turns[nodeId] < turns[target]) {
+            target = nodeId
+            best = units
+        }
+    }
+    return target, best >= 0
+}
+
+/*
+orders gathering my units on the node closest to target, and sending them in once there are enough.
+Returns false if the attack can't go ahead: nothing of mine can reach the target, or there aren't enough units on the
+staging node, none are on their way there and it has stopped growing.
+*/
+func (self *deadlockBreaker) attack(me state.PlayerId, s *state.State, distances *common.DistanceMatrix) (result state.Orders, ok bool) {
+    nodeIds := common.SortedNodeIds(s)
+    staging := state.NodeId("")
+    stagingDist := common.UNREACHABLE
+    for _, nodeId := range nodeIds {
+        if s.Nodes[nodeId].Units[me] > 0 {
+            if dist := distances.Distance(nodeId, self.target); dist < stagingDist {
+                staging = nodeId
+                stagingDist = dist
+            }
+        }
+    }
+    if stagingDist == common.UNREACHABLE {
+        return
+    }
+    for _, nodeId := range nodeIds {
+        units := s.Nodes[nodeId].Units[me] - 1
+        if units <= 0 || nodeId == staging {
+            continue
+        }
+        result = append(result, state.Order{
+            Src:   nodeId,
+            Dst:   distances.NextHop(nodeId, staging),
+            Units: units,
+        })
+    }
+    // enough to beat what will be on the target when we get there, including anything on its way
+    target := s.Nodes[self.target]
+    needed := 1 + common.GrowFor(target.Units[self.owner], target.Size, stagingDist)
+    unitCounts := common.CountAllUnits(me, s)
+    arrivals := unitCounts[self.target].Arrivals
+    for _, turn := range arrivals.Turns() {
+        if turn <= stagingDist {
+            needed += arrivals.Enemy(me, turn)
+        }
+    }
+    node := s.Nodes[staging]
+    if units := node.Units[me] - 1; units >= needed {
+        result = append(result, state.Order{
+            Src:   staging,
+            Dst:   distances.NextHop(staging, self.target),
+            Units: units,
+        })
+    } else if len(result) == 0 && unitCounts[staging].Arrivals.FirstFriendly(me) == common.DELAY_NO_UNITS &&
+        common.Grow(node.Units[me], node.Size) == node.Units[me] {
+        return
+    }
+    return result, true
+}
+
+/*
+breakDeadlock returns orders for an attack if the game is (or was, and we are still attacking) in a stalemate,
+and false if we should just keep balancing
+*/
+func breakDeadlock(logger stockholmCommon.Logger, version string, me state.PlayerId, s *state.State) (result state.Orders, attacking bool) {
+    breaker := getDeadlockBreaker(version, me, s)
+    breaker.mutex.Lock()
+    defer breaker.mutex.Unlock()
+
+    stable, stalemate := breaker.detector.Observe(s)
+    distances := common.GetTopology(s).Distances
+    if breaker.target != "" {
+        // is the attack over?
+        if owner(s.Nodes[breaker.target]) != breaker.owner || s.Turn-breaker.started > ATTACK_TURNS {
+            logger.Printf("Attack on %v is over", breaker.target)
+            breaker.target = ""
+        }
+    }
+    if breaker.target == "" {
+        if !stalemate {
+            return
+        }
+        target, found := weakestNeighbour(me, s, distances)
+        if !found {
+            return
+        }
+        logger.Printf("Stalemate for %v turns, attacking %v", stable, target)
+        breaker.target = target
+        breaker.owner = owner(s.Nodes[target])
+        breaker.started = s.Turn
+    }
+    result, ok := breaker.attack(me, s, distances)
+    if !ok {
+        logger.Printf("Attack on %v can't go ahead, balancing instead", breaker.target)
+        breaker.target = ""
+        return nil, false
+    }
+
+    // drop or fix any invalid orders
+    result, changes := common.NormalizeOrders(me, s, result)
+    for _, change := range changes {
+        logger.Printf("NormalizeOrders %v", change)
+    }
+    return result, true
+}
@@ -0,0 +1,173 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+)
+
+/*
+   Combat and growth rules, mirroring what state.State.Next does each turn:
+   1. orders are executed and units move one step along their edges
+   2. units reaching the end of an edge land on edge.Dst
+   3. if several players have units on a node they fight: the biggest army survives with
+      (biggest - second biggest) units, everyone else dies. Equal biggest armies wipe each other out.
+   4. if a single player holds a node with less than node.Size units, the units grow (see Grow)
+*/
+
+// NodeForecast describes who holds a node at the end of a turn
+type NodeForecast struct {
+    Owner state.PlayerId // empty if nobody has units on the node
+    Units int
+}
+
+// Fight resolves a battle between all players in units and returns the survivors
+func Fight(units map[state.PlayerId]int) (result map[state.PlayerId]int) {
+    result = make(map[state.PlayerId]int, 1)
+    var winner state.PlayerId
+    best, second := 0, 0
+    for player, numUnits := range units {
+        if numUnits > best {
+            second = best
+            best = numUnits
+            winner = player
+        } else if numUnits > second {
+            second = numUnits
+        }
+    }
+    if best > second {
+        result[winner] = best - second
+    }
+    return
+}
+
+// step plays out a single turn on a node: arrivals land, armies fight and the survivor grows
+func step(size int, units map[state.PlayerId]int, arriving map[state.PlayerId]int) (result map[state.PlayerId]int) {
+    present := make(map[state.PlayerId]int, len(units)+len(arriving))
+    for player, numUnits := range units {
+        present[player] += numUnits
+    }
+    for player, numUnits := range arriving {
+        present[player] += numUnits
+    }
+    result = Fight(present)
+    for player, numUnits := range result {
+        result[player] = Grow(numUnits, size)
+    }
+    return
+}
+
+/*
+PredictCombat plays out the next turns on node, assuming nobody issues new orders.
+units are the units currently on the node per player (usually node.Units) and arrivals are the
+units on their way (usually UnitCounts.Arrivals). result[i] is the forecast after i+1 turns.
+*/
+func PredictCombat(node *state.Node, units map[state.PlayerId]int, arrivals Timeline, turns int) (result []NodeForecast) {
+    result = make([]NodeForecast, 0, turns)
+    // units on a node fight straight away, so resolve that before the first turn
+    current := Fight(units)
+    for turn := 1; turn <= turns; turn++ {
+        current = step(node.Size, current, arrivals[turn])
+        forecast := NodeForecast{}
+        for player, numUnits := range current {
+            forecast.Owner = player
+            forecast.Units = numUnits
+        }
+        result = append(result, forecast)
+    }
+    return
+}
+
+/*
+UnitsToCapture returns how many of my units must land on node in delay turns (delay >= 1) for me to
+hold it after the fight on that turn, given the units already there and the ones already on their way.
+Returns 0 if I will hold the node anyway.
+*/
+func UnitsToCapture(me state.PlayerId, node *state.Node, units map[state.PlayerId]int, arrivals Timeline, delay int) int {
+    current := Fight(units)
+    for turn := 1; turn < delay; turn++ {
+        current = step(node.Size, current, arrivals[turn])
+    }
+    // add up everything on the node when my units land
+    present := make(map[state.PlayerId]int, len(current)+len(arrivals[delay]))
+    for player, numUnits := range current {
+        present[player] += numUnits
+    }
+    for player, numUnits := range arrivals[delay] {
+        present[player] += numUnits
+    }
+    strongestEnemy := 0
+    for player, numUnits := range present {
+        if player != me && numUnits > strongestEnemy {
+            strongestEnemy = numUnits
+        }
+    }
+    if needed := strongestEnemy + 1 - present[me]; needed > 0 {
+        return needed
+    }
+    return 0
+}
+
+/*
+UnitsToHold returns the smallest number of my units that, standing on node now, still own it every turn until the
+last enemy in arrivals has landed. My own arrivals are ignored, since they may be sent somewhere else.
+Returns 0 if no enemies are coming, and max+1 if even max units can't hold the node.
+*/
+func UnitsToHold(me state.PlayerId, node *state.Node, arrivals Timeline, max int) int {
+    enemies := make(Timeline)
+    for delay, unitMap := range arrivals {
+        for player, numUnits := range unitMap {
+            if player != me && numUnits > 0 {
+                enemies.addUnits(delay, player, numUnits)
+            }
+        }
+    }
+    return smallestHold(me, node, enemies, 1, max)
+}
+
+/*
+ReinforcementsToHold returns how many more of my units node needs now, on top of the units of mine already there,
+to still own it every turn until the last enemy in arrivals has landed. Unlike UnitsToHold it counts my own
+arrivals, each on the turn it lands. Returns 0 if no more are needed, and max+1 if even max more can't hold the node.
+*/
+func ReinforcementsToHold(me state.PlayerId, node *state.Node, units int, arrivals Timeline, max int) int {
+    if needed := smallestHold(me, node, arrivals, units, units+max); needed > units {
+        return needed - units
+    }
+    return 0
+}
+
+/*
+the smallest number of my units between low and high that, standing on node now, own it every turn until the last
+enemy in arrivals has landed. Returns 0 if no enemies are coming and high+1 if none of them hold.
+*/
+func smallestHold(me state.PlayerId, node *state.Node, arrivals Timeline, low, high int) int {
+    turns := 0
+    for _, turn := range arrivals.Turns() {
+        if arrivals.Enemy(me, turn) > 0 {
+            turns = turn
+        }
+    }
+    if turns == 0 {
+        return 0
+    }
+    holds := func(units int) bool {
+        for _, forecast := range PredictCombat(node, map[state.PlayerId]int{me: units}, arrivals, turns) {
+            if forecast.Owner != me {
+                return false
+            }
+        }
+        return true
+    }
+    if high < low || high < 1 || !holds(high) {
+        return high + 1
+    }
+    // binary search for the smallest number that holds
+    for low < high {
+        middle := (low + high) / 2
+        if holds(middle) {
+            high = middle
+        } else {
+            low = middle + 1
+        }
+    }
+    return low
+}
@@ -0,0 +1,185 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "log"
+    "os"
+    "testing"
+)
+
+// builds a line of nodes a - b - c with the given edge lengths, nobody has any units yet
+func lineState(size, lengthAB, lengthBC int) *state.State {
+    s := &state.State{Nodes: make(map[state.NodeId]*state.Node)}
+    for _, id := range []state.NodeId{"a", "b", "c"} {
+        s.Nodes[id] = &state.Node{
+            Id:    id,
+            Size:  size,
+            Units: make(map[state.PlayerId]int),
+            Edges: make(map[state.NodeId]state.Edge),
+        }
+    }
+    connect(s, "a", "b", lengthAB)
+    connect(s, "b", "c", lengthBC)
+    return s
+}
+
+// adds edges in both directions between src and dst
+func connect(s *state.State, src, dst state.NodeId, length int) {
+    s.Nodes[src].Edges[dst] = state.Edge{Src: src, Dst: dst, Units: make([]state.Units, length)}
+    s.Nodes[dst].Edges[src] = state.Edge{Src: dst, Dst: src, Units: make([]state.Units, length)}
+}
+
+func TestFight(t *testing.T) {
+    result := Fight(map[state.PlayerId]int{"a": 10, "b": 4, "c": 7})
+    if len(result) != 1 || result["a"] != 3 {
+        t.Errorf("expected a to survive with 3 units, got %v", result)
+    }
+    result = Fight(map[state.PlayerId]int{"a": 5, "b": 5})
+    if len(result) != 0 {
+        t.Errorf("expected equal armies to wipe each other out, got %v", result)
+    }
+}
+
+func TestPredictCombat(t *testing.T) {
+    logger := log.New(os.Stdout, "", 0)
+    turns := 8
+
+    s := lineState(30, 3, 2)
+    s.Nodes["b"].Units["x"] = 6
+    s.Nodes["a"].Units["y"] = 20
+    s.Nodes["c"].Units["z"] = 3
+    // x is defending b, y and z attack it from both sides at different times
+    orders := map[state.PlayerId]state.Orders{
+        "y": state.Orders{state.Order{Src: "a", Dst: "b", Units: 15}},
+        "z": state.Orders{state.Order{Src: "c", Dst: "b", Units: 2}},
+    }
+    s.Next(logger, orders)
+
+    counts := CountAllUnits("x", s)
+    predicted := PredictCombat(s.Nodes["b"], s.Nodes["b"].Units, counts["b"].Arrivals, turns)
+
+    for turn := 0; turn < turns; turn++ {
+        s.Next(logger, map[state.PlayerId]state.Orders{})
+        actual := NodeForecast{}
+        for player, numUnits := range s.Nodes["b"].Units {
+            if numUnits > 0 {
+                actual = NodeForecast{player, numUnits}
+            }
+        }
+        if actual != predicted[turn] {
+            t.Errorf("turn %v: predicted %+v but state.Next gave %+v", turn+1, predicted[turn], actual)
+        }
+    }
+}
+
+func TestUnitsToCapture(t *testing.T) {
+    logger := log.New(os.Stdout, "", 0)
+
+    s := lineState(30, 1, 3)
+    s.Nodes["b"].Units["x"] = 4
+    s.Nodes["c"].Units["z"] = 20
+    s.Next(logger, map[state.PlayerId]state.Orders{
+        "z": state.Orders{state.Order{Src: "c", Dst: "b", Units: 10}},
+    })
+    counts := CountAllUnits("y", s)
+    needed := UnitsToCapture("y", s.Nodes["b"], s.Nodes["b"].Units, counts["b"].Arrivals, 1)
+    if needed <= 0 {
+        t.Fatalf("expected to need some units to capture b, got %v", needed)
+    }
+
+    // sending exactly that many from a must leave y holding b, one less must not
+    for _, send := range []int{needed, needed - 1} {
+        sim := lineState(30, 1, 3)
+        sim.Nodes["b"].Units["x"] = 4
+        sim.Nodes["c"].Units["z"] = 20
+        sim.Nodes["a"].Units["y"] = send + 1
+        sim.Next(logger, map[state.PlayerId]state.Orders{
+            "z": state.Orders{state.Order{Src: "c", Dst: "b", Units: 10}},
+        })
+        sim.Next(logger, map[state.PlayerId]state.Orders{
+            "y": state.Orders{state.Order{Src: "a", Dst: "b", Units: send}},
+        })
+        holds := sim.Nodes["b"].Units["y"] > 0
+        if holds != (send == needed) {
+            t.Errorf("sending %v units (needed %v): y holds b = %v", send, needed, holds)
+        }
+    }
+}
+
+func TestUnitsToHold(t *testing.T) {
+    logger := log.New(os.Stdout, "", 0)
+
+    s := lineState(100, 1, 3)
+    s.Nodes["c"].Units["z"] = 30
+    s.Next(logger, map[state.PlayerId]state.Orders{
+        "z": state.Orders{state.Order{Src: "c", Dst: "b", Units: 20}},
+    })
+    counts := CountAllUnits("y", s)
+    if needed := UnitsToHold("y", s.Nodes["a"], counts["a"].Arrivals, 50); needed != 0 {
+        t.Errorf("expected no units needed to hold a, got %v", needed)
+    }
+    needed := UnitsToHold("y", s.Nodes["b"], counts["b"].Arrivals, 50)
+    if needed <= 1 || needed > 20 {
+        t.Fatalf("expected to need between 2 and 20 units to hold b, got %v", needed)
+    }
+    if tooFew := UnitsToHold("y", s.Nodes["b"], counts["b"].Arrivals, needed-1); tooFew != needed {
+        t.Errorf("expected %v units not to be enough to hold b, got %v", needed-1, tooFew)
+    }
+
+    // leaving exactly that many on b must hold it, one less must not
+    for _, keep := range []int{needed, needed - 1} {
+        sim, err := CopyState(s)
+        if err != nil {
+            t.Fatal(err)
+        }
+        sim.Nodes["b"].Units["y"] = keep
+        for turn := 0; turn < 3; turn++ {
+            sim.Next(logger, map[state.PlayerId]state.Orders{})
+        }
+        holds := sim.Nodes["b"].Units["y"] > 0
+        if holds != (keep == needed) {
+            t.Errorf("keeping %v units (needed %v): y holds b = %v", keep, needed, holds)
+        }
+    }
+}
+
+func TestReinforcementsToHold(t *testing.T) {
+    logger := log.New(os.Stdout, "", 0)
+
+    // z lands 20 units on b in 2 turns, y has a few units there and 5 more landing in 1 turn
+    s := lineState(100, 2, 3)
+    s.Nodes["b"].Units["y"] = 2
+    s.Nodes["c"].Units["z"] = 30
+    s.Next(logger, map[state.PlayerId]state.Orders{
+        "z": state.Orders{state.Order{Src: "c", Dst: "b", Units: 20}},
+    })
+    s.Nodes["a"].Edges["b"].Units[1] = state.Units{"y": 5}
+    arrivals := CountAllUnits("y", s)["b"].Arrivals
+    if first := arrivals.FirstEnemy("y"); first != 2 {
+        t.Fatalf("expected z to land in 2 turns, got %v", first)
+    }
+    units := s.Nodes["b"].Units["y"]
+    missing := ReinforcementsToHold("y", s.Nodes["b"], units, arrivals, 50)
+    if missing <= 0 || missing >= UnitsToHold("y", s.Nodes["b"], arrivals, 50)-units {
+        t.Fatalf("expected the 5 units landing first to lower what is missing, got %v", missing)
+    }
+    if none := ReinforcementsToHold("y", s.Nodes["a"], 0, CountAllUnits("y", s)["a"].Arrivals, 50); none != 0 {
+        t.Errorf("expected nothing missing on a, got %v", none)
+    }
+
+    // sending exactly that many more holds b, one less doesn't
+    for _, extra := range []int{missing, missing - 1} {
+        sim, err := CopyState(s)
+        if err != nil {
+            t.Fatal(err)
+        }
+        sim.Nodes["b"].Units["y"] += extra
+        for turn := 0; turn < 3; turn++ {
+            sim.Next(logger, map[state.PlayerId]state.Orders{})
+        }
+        holds := sim.Nodes["b"].Units["y"] > 0
+        if holds != (extra == missing) {
+            t.Errorf("adding %v units (missing %v): y holds b = %v", extra, missing, holds)
+        }
+    }
+}
@@ -12,10 +12,20 @@ func Min(a, b int) int {
     return b
 }
 
+// integer Max function instead of using math.Max
+func Max(a, b int) int {
+    if a > b {
+        return a
+    }
+    return b
+}
+
 /*
    Delay == 0                   - node is claimed
    0 < Delay < DELAY_NO_UNITS   - some units will arrive in Delay turns
    Delay = DELAY_NO_UNITS       - no units approaching
+
+   Arrivals keeps the full per-turn breakdown that Delay summarizes.
 */
 const DELAY_NO_UNITS = 1000
 
@@ -23,20 +33,21 @@ type UnitCounts struct {
     Units      int
     EnemyUnits int
     Delay      int
-    // EnemyDelay int
-    Adjacent bool
+    Adjacent   bool
+    // units on edges heading to this node, per turn and player
+    Arrivals Timeline
 }
 
 func NewUnitCounts() *UnitCounts {
-    return &UnitCounts{0, 0, DELAY_NO_UNITS, false}
+    return &UnitCounts{0, 0, DELAY_NO_UNITS, false, make(Timeline)}
 }
 
 func (self *UnitCounts) add(u *UnitCounts) {
     self.Units += u.Units
     self.EnemyUnits += u.EnemyUnits
     self.Delay = Min(self.Delay, u.Delay)
-    // self.EnemyDelay = math.Min(self.EnemyDelay, u.EnemyDelay)
     self.Adjacent = self.Adjacent || u.Adjacent
+    self.Arrivals.merge(u.Arrivals)
 }
 
 func CountEdgeUnits(me state.PlayerId, edge *state.Edge) (u *UnitCounts) {
@@ -53,6 +64,7 @@ func CountEdgeUnits(me state.PlayerId, edge *state.Edge) (u *UnitCounts) {
             }
         }
     }
+    u.Arrivals = EdgeTimeline(edge)
     return
 }
 
@@ -83,10 +95,12 @@ func CountAllUnits(me state.PlayerId, s *state.State) (result map[state.NodeId]*
     }
     // calculate result
     for nodeId, node := range s.Nodes {
-        result[nodeId].add(CountNodeUnits(me, node))
+        nodeCounts := CountNodeUnits(me, node)
+        result[nodeId].add(nodeCounts)
         for _, edge := range node.Edges {
             result[edge.Dst].add(CountEdgeUnits(me, &edge))
-            if result[edge.Src].Units > 0 {
+            // only look at units on the node itself, result[edge.Src] may or may not include edges yet
+            if nodeCounts.Units > 0 {
                 result[edge.Dst].Adjacent = true
             }
         }
@@ -0,0 +1,125 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "math"
+    "sort"
+)
+
+// distance between two nodes that are not connected at all
+const UNREACHABLE = math.MaxInt32
+
+/*
+DistanceMatrix holds the shortest distance (in turns, using len(edge.Units) as the length of each edge)
+and the first hop of the shortest path between every pair of nodes in a state.
+Build it once per Orders call with NewDistanceMatrix instead of calling s.Path in a loop.
+*/
+type DistanceMatrix struct {
+    ids   []state.NodeId
+    index map[state.NodeId]int
+    dist  [][]int
+    next  [][]int
+}
+
+// NewDistanceMatrix runs dijkstra from every node in s
+func NewDistanceMatrix(s *state.State) (result *DistanceMatrix) {
+    result = &DistanceMatrix{
+        index: make(map[state.NodeId]int, len(s.Nodes)),
+    }
+    // sorted so that ties between equally short paths are always broken the same way
+    result.ids = SortedNodeIds(s)
+    for i, nodeId := range result.ids {
+        result.index[nodeId] = i
+    }
+    // adjacency list of every node, sorted by neighbour
+    links := make([]linkList, len(result.ids))
+    for i, nodeId := range result.ids {
+        for _, edge := range s.Nodes[nodeId].Edges {
+            if dst, found := result.index[edge.Dst]; found {
+                links[i] = append(links[i], link{dst, len(edge.Units)})
+            }
+        }
+        sort.Sort(links[i])
+    }
+    result.dist = make([][]int, len(result.ids))
+    result.next = make([][]int, len(result.ids))
+    for src := range result.ids {
+        dist := make([]int, len(result.ids))
+        next := make([]int, len(result.ids))
+        done := make([]bool, len(result.ids))
+        for i := range dist {
+            dist[i] = UNREACHABLE
+            next[i] = src
+        }
+        dist[src] = 0
+        for {
+            // pick the closest node not visited yet
+            current := -1
+            for i := range dist {
+                if !done[i] && dist[i] < UNREACHABLE && (current < 0 || dist[i] < dist[current]) {
+                    current = i
+                }
+            }
+            if current < 0 {
+                break
+            }
+            done[current] = true
+            for _, l := range links[current] {
+                if d := dist[current] + l.length; d < dist[l.dst] {
+                    dist[l.dst] = d
+                    if current == src {
+                        next[l.dst] = l.dst
+                    } else {
+                        next[l.dst] = next[current]
+                    }
+                }
+            }
+        }
+        result.dist[src] = dist
+        result.next[src] = next
+    }
+    return
+}
+
+// Distance returns the number of turns it takes to get from src to dst, or UNREACHABLE
+func (self *DistanceMatrix) Distance(src, dst state.NodeId) int {
+    i, foundSrc := self.index[src]
+    j, foundDst := self.index[dst]
+    if !foundSrc || !foundDst {
+        return UNREACHABLE
+    }
+    return self.dist[i][j]
+}
+
+// NextHop returns the neighbour of src that the shortest path to dst starts with.
+// Returns src itself if src == dst or dst can't be reached.
+func (self *DistanceMatrix) NextHop(src, dst state.NodeId) state.NodeId {
+    i, foundSrc := self.index[src]
+    j, foundDst := self.index[dst]
+    if !foundSrc || !foundDst {
+        return src
+    }
+    return self.ids[self.next[i][j]]
+}
+
+// Path returns the nodes on the shortest path from src to dst, excluding src (the same format as s.Path)
+func (self *DistanceMatrix) Path(src, dst state.NodeId) (result []state.NodeId) {
+    if self.Distance(src, dst) == UNREACHABLE {
+        return
+    }
+    for current := src; current != dst; {
+        current = self.NextHop(current, dst)
+        result = append(result, current)
+    }
+    return
+}
+
+// an edge in the adjacency list of a DistanceMatrix
+type link struct{ dst, length int }
+
+// sortable list of links
+type linkList []link
+
+func (s linkList) Len() int           { return len(s) }
+func (s linkList) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s linkList) Less(i, j int) bool { return s[i].dst < s[j].dst }
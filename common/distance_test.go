@@ -0,0 +1,42 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "log"
+    "os"
+    "testing"
+)
+
+// total length in turns of a path as returned by s.Path
+func pathLength(s *state.State, src state.NodeId, path []state.NodeId) (result int) {
+    for _, hop := range path {
+        for _, edge := range s.Nodes[src].Edges {
+            if edge.Dst == hop {
+                result += len(edge.Units)
+            }
+        }
+        src = hop
+    }
+    return
+}
+
+func TestDistanceMatrix(t *testing.T) {
+    logger := log.New(os.Stdout, "", 0)
+    s := state.RandomState(logger, []state.PlayerId{"a", "b"})
+    distances := NewDistanceMatrix(s)
+    for src := range s.Nodes {
+        for dst := range s.Nodes {
+            expected := pathLength(s, src, s.Path(src, dst, nil))
+            if dist := distances.Distance(src, dst); dist != expected {
+                t.Errorf("distance %v -> %v: expected %v, got %v", src, dst, expected, dist)
+            }
+            path := distances.Path(src, dst)
+            if length := pathLength(s, src, path); length != expected {
+                t.Errorf("path %v -> %v: %v has length %v, expected %v", src, dst, path, length, expected)
+            }
+            if src != dst && (len(path) == 0 || path[0] != distances.NextHop(src, dst) || path[len(path)-1] != dst) {
+                t.Errorf("path %v -> %v: %v does not start with next hop %v", src, dst, path, distances.NextHop(src, dst))
+            }
+        }
+    }
+}
@@ -0,0 +1,147 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+)
+
+/*
+   FlowNetwork is a min-cost max-flow solver using successive shortest paths (bellman-ford).
+   Vertices are numbered 0..n-1. Costs may be negative as long as there are no negative cycles.
+*/
+type FlowNetwork struct {
+    edges []flowEdge
+    adj   [][]int // indexes into edges for each vertex
+}
+
+type flowEdge struct {
+    to, capacity, cost, flow int
+}
+
+// NewFlowNetwork creates a network with n vertices and no edges
+func NewFlowNetwork(n int) *FlowNetwork {
+    return &FlowNetwork{adj: make([][]int, n)}
+}
+
+// AddEdge adds a directed edge and returns its id, which can be passed to Flow after solving
+func (self *FlowNetwork) AddEdge(from, to, capacity, cost int) (id int) {
+    id = len(self.edges)
+    self.edges = append(self.edges, flowEdge{to, capacity, cost, 0}, flowEdge{from, 0, -cost, 0})
+    self.adj[from] = append(self.adj[from], id)
+    self.adj[to] = append(self.adj[to], id+1)
+    return
+}
+
+// Flow returns the flow on the edge with the given id
+func (self *FlowNetwork) Flow(id int) int {
+    return self.edges[id].flow
+}
+
+// MinCostMaxFlow pushes as much flow as possible from source to sink, as cheaply as possible
+func (self *FlowNetwork) MinCostMaxFlow(source, sink int) (flow, cost int) {
+    n := len(self.adj)
+    for {
+        // find the cheapest path with spare capacity
+        dist := make([]int, n)
+        via := make([]int, n) // edge used to reach each vertex
+        for i := range dist {
+            dist[i] = UNREACHABLE
+            via[i] = -1
+        }
+        dist[source] = 0
+        for changed, round := true, 0; changed && round < n; round++ {
+            changed = false
+            for v := 0; v < n; v++ {
+                if dist[v] == UNREACHABLE {
+                    continue
+                }
+                for _, id := range self.adj[v] {
+                    e := self.edges[id]
+                    if e.capacity-e.flow > 0 && dist[v]+e.cost < dist[e.to] {
+                        dist[e.to] = dist[v] + e.cost
+                        via[e.to] = id
+                        changed = true
+                    }
+                }
+            }
+        }
+        if dist[sink] == UNREACHABLE {
+            return
+        }
+        // push as much as the path allows
+        push := UNREACHABLE
+        for v := sink; v != source; v = self.edges[via[v]^1].to {
+            e := self.edges[via[v]]
+            push = Min(push, e.capacity-e.flow)
+        }
+        for v := sink; v != source; v = self.edges[via[v]^1].to {
+            self.edges[via[v]].flow += push
+            self.edges[via[v]^1].flow -= push
+        }
+        flow += push
+        cost += push * dist[sink]
+    }
+}
+
+// Supply is a packet of Units soldiers that are (or will be, in Delay turns) available on Node
+type Supply struct {
+    Node  state.NodeId
+    Delay int
+    Units int
+}
+
+// Demand asks for Units soldiers to be sent to Node
+type Demand struct {
+    Node  state.NodeId
+    Units int
+}
+
+// Assignment sends Units soldiers from Supply towards Target, arriving after Arrival turns
+type Assignment struct {
+    Supply  Supply
+    Target  state.NodeId
+    Units   int
+    Arrival int
+}
+
+/*
+AllocateSoldiers assigns supplies to demands so that as many demanded soldiers as possible are delivered,
+and of those the total arrival time (soldiers * turns) is as small as possible.
+Supplies that are not needed are left out of the result.
+*/
+func AllocateSoldiers(supplies []Supply, demands []Demand, distances *DistanceMatrix) (result []Assignment) {
+    // vertices: 0 source, 1 sink, then supplies, then demands
+    source, sink := 0, 1
+    network := NewFlowNetwork(2 + len(supplies) + len(demands))
+    for i, supply := range supplies {
+        network.AddEdge(source, 2+i, supply.Units, 0)
+    }
+    for j, demand := range demands {
+        network.AddEdge(2+len(supplies)+j, sink, demand.Units, 0)
+    }
+    // every supply/demand pair that can be connected
+    type candidate struct{ supply, demand, edge, arrival int }
+    candidates := make([]candidate, 0, len(supplies)*len(demands))
+    for i, supply := range supplies {
+        for j, demand := range demands {
+            dist := distances.Distance(supply.Node, demand.Node)
+            if dist == UNREACHABLE || supply.Units <= 0 || demand.Units <= 0 {
+                continue
+            }
+            arrival := supply.Delay + dist
+            edge := network.AddEdge(2+i, 2+len(supplies)+j, supply.Units, arrival)
+            candidates = append(candidates, candidate{i, j, edge, arrival})
+        }
+    }
+    network.MinCostMaxFlow(source, sink)
+    for _, c := range candidates {
+        if units := network.Flow(c.edge); units > 0 {
+            result = append(result, Assignment{
+                Supply:  supplies[c.supply],
+                Target:  demands[c.demand].Node,
+                Units:   units,
+                Arrival: c.arrival,
+            })
+        }
+    }
+    return
+}
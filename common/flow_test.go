@@ -0,0 +1,31 @@
+package common
+
+import (
+    "testing"
+)
+
+func TestAllocateSoldiers(t *testing.T) {
+    s := lineState(30, 3, 2)
+    distances := NewDistanceMatrix(s)
+    // sending the soldiers on c to b (the closest) would force the ones on a to walk all the way to c
+    supplies := []Supply{
+        Supply{Node: "a", Units: 2},
+        Supply{Node: "c", Units: 2},
+        Supply{Node: "b", Delay: 1, Units: 4},
+    }
+    demands := []Demand{
+        Demand{Node: "b", Units: 2},
+        Demand{Node: "c", Units: 3},
+    }
+    result := AllocateSoldiers(supplies, demands, distances)
+
+    total, cost := 0, 0
+    for _, assignment := range result {
+        total += assignment.Units
+        cost += assignment.Units * assignment.Arrival
+    }
+    // best: 2 from b to b (1 turn each), 2 from c to c (0), 1 from b to c (1 + 2 turns)
+    if total != 5 || cost != 5 {
+        t.Errorf("expected 5 soldiers delivered at a total cost of 5, got %v at %v: %+v", total, cost, result)
+    }
+}
@@ -0,0 +1,176 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+)
+
+// how many games on the same map GameOf keeps apart, the least recently seen one is forgotten first
+const GAMES_PER_TOPOLOGY = 16
+
+/*
+Game holds what an AI remembers about one game, like a stalemate detector. Get it with GameOf.
+All methods are safe to use from concurrent requests.
+*/
+type Game struct {
+    values valueStore
+    last   *snapshot // the last state seen of this game
+}
+
+// Value returns the value stored under key for this game, calling compute to create it the first time, see Topology.Value
+func (self *Game) Value(key string, compute func() interface{}) interface{} {
+    return self.values.value(key, compute)
+}
+
+// the units on every node and edge of a state, and its turn
+type snapshot struct {
+    turn  int
+    nodes map[state.NodeId]state.Units
+    edges map[state.NodeId]map[state.NodeId][]state.Units
+}
+
+// copies the units of units that are > 0
+func copyUnits(units state.Units) (result state.Units) {
+    result = make(state.Units, len(units))
+    for player, numUnits := range units {
+        if numUnits > 0 {
+            result[player] = numUnits
+        }
+    }
+    return
+}
+
+// true if a and b have the same units > 0
+func sameUnits(a, b state.Units) bool {
+    a, b = copyUnits(a), copyUnits(b)
+    if len(a) != len(b) {
+        return false
+    }
+    for player, numUnits := range a {
+        if b[player] != numUnits {
+            return false
+        }
+    }
+    return true
+}
+
+func newSnapshot(s *state.State) *snapshot {
+    result := &snapshot{
+        turn:  s.Turn,
+        nodes: make(map[state.NodeId]state.Units, len(s.Nodes)),
+        edges: make(map[state.NodeId]map[state.NodeId][]state.Units, len(s.Nodes)),
+    }
+    for nodeId, node := range s.Nodes {
+        result.nodes[nodeId] = copyUnits(node.Units)
+        result.edges[nodeId] = make(map[state.NodeId][]state.Units, len(node.Edges))
+        for _, edge := range node.Edges {
+            slots := make([]state.Units, len(edge.Units))
+            for index, unitMap := range edge.Units {
+                slots[index] = copyUnits(unitMap)
+            }
+            result.edges[nodeId][edge.Dst] = slots
+        }
+    }
+    return result
+}
+
+// true if s is the state of the snapshot, e.g. when another player of the same game asks for orders
+func (self *snapshot) same(s *state.State) bool {
+    if s.Turn != self.turn {
+        return false
+    }
+    for nodeId, node := range s.Nodes {
+        if !sameUnits(node.Units, self.nodes[nodeId]) {
+            return false
+        }
+        for _, edge := range node.Edges {
+            for index, unitMap := range edge.Units {
+                if !sameUnits(unitMap, self.edges[nodeId][edge.Dst][index]) {
+                    return false
+                }
+            }
+        }
+    }
+    return true
+}
+
+// true if s can be a later turn of the snapshot: every unit on an edge has moved one step along it each turn
+func (self *snapshot) follows(s *state.State) bool {
+    turns := s.Turn - self.turn
+    if turns < 1 {
+        return false
+    }
+    for nodeId, node := range s.Nodes {
+        for _, edge := range node.Edges {
+            // units sent since then are only on the first turns+1 slots, Next moves them one step right away
+            for index := turns + 1; index < len(edge.Units); index++ {
+                if !sameUnits(edge.Units[index], self.edges[nodeId][edge.Dst][index-turns]) {
+                    return false
+                }
+            }
+        }
+    }
+    return true
+}
+
+// how many units on the nodes of s are different from the snapshot
+func (self *snapshot) difference(s *state.State) (result int) {
+    for nodeId, node := range s.Nodes {
+        for player, numUnits := range copyUnits(node.Units) {
+            result += Max(numUnits, self.nodes[nodeId][player]) - Min(numUnits, self.nodes[nodeId][player])
+        }
+        for player, numUnits := range self.nodes[nodeId] {
+            if node.Units[player] <= 0 {
+                result += numUnits
+            }
+        }
+    }
+    return
+}
+
+/*
+GameOf returns the game that s belongs to. The AI interface doesn't say which game a state is from, so it is worked
+out from the games on the same map: s belongs to the game whose last seen state it is, or else to the game it can be
+a later turn of with the units on its nodes closest to s. Anything else starts a new game. Games that can't be told
+apart this way (e.g. identical ones on their first turns) are mixed up until they differ, then one of them starts over.
+*/
+func GameOf(s *state.State) *Game {
+    return GetTopology(s).game(s)
+}
+
+// the game of s on this topology, see GameOf
+func (self *Topology) game(s *state.State) (result *Game) {
+    self.gamesMutex.Lock()
+    defer self.gamesMutex.Unlock()
+    found, seen := -1, false
+    for index, game := range self.games {
+        if game.last.same(s) {
+            found, seen = index, true
+            break
+        }
+    }
+    if found < 0 {
+        best := 0
+        for index, game := range self.games {
+            if game.last.follows(s) {
+                if difference := game.last.difference(s); found < 0 || difference < best {
+                    found, best = index, difference
+                }
+            }
+        }
+    }
+    if found < 0 {
+        result = &Game{}
+    } else {
+        result = self.games[found]
+        self.games = append(self.games[:found], self.games[found+1:]...)
+    }
+    if !seen {
+        result.last = newSnapshot(s)
+    }
+    // most recently seen first
+    self.games = append([]*Game{result}, self.games...)
+    if len(self.games) > GAMES_PER_TOPOLOGY {
+        self.games = self.games[:GAMES_PER_TOPOLOGY]
+    }
+    return
+}
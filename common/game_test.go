@@ -0,0 +1,55 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "io/ioutil"
+    "log"
+    "testing"
+)
+
+func TestGameOf(t *testing.T) {
+    logger := log.New(ioutil.Discard, "", 0)
+    // two games on the same map with the same players, x sends a different number of units to b in each
+    newGame := func(units int) *state.State {
+        s := lineState(37, 6, 2)
+        s.Nodes["a"].Units["x"] = 10
+        s.Nodes["c"].Units["y"] = 10
+        s.Next(logger, map[state.PlayerId]state.Orders{"x": {{Src: "a", Dst: "b", Units: units}}})
+        return s
+    }
+    first, second := newGame(2), newGame(3)
+    firstGame, secondGame := GameOf(first), GameOf(second)
+    if firstGame == secondGame {
+        t.Fatalf("expected different games")
+    }
+    if GameOf(first) != firstGame {
+        t.Errorf("expected the same state to be the same game")
+    }
+
+    // whatever happens on later turns, the units already on their way tell the games apart
+    for turn := 0; turn < 2; turn++ {
+        for _, s := range []*state.State{second, first} {
+            s.Next(logger, map[state.PlayerId]state.Orders{"y": {{Src: "c", Dst: "b", Units: 1}}})
+        }
+        if GameOf(first) != firstGame || GameOf(second) != secondGame {
+            t.Errorf("turn %v: expected each state to stay in its game", first.Turn)
+        }
+    }
+    // also when a turn was skipped
+    first.Next(logger, map[state.PlayerId]state.Orders{})
+    first.Next(logger, map[state.PlayerId]state.Orders{})
+    if GameOf(first) != firstGame {
+        t.Errorf("expected a state 2 turns later to stay in its game")
+    }
+
+    // a game starting over on the same map is a new game
+    if game := GameOf(newGame(2)); game == firstGame || game == secondGame {
+        t.Errorf("expected a new game")
+    }
+
+    // values are kept per game
+    firstGame.Value("plan", func() interface{} { return 1 })
+    if value := secondGame.Value("plan", func() interface{} { return 2 }); value != 2 {
+        t.Errorf("expected the value of the second game, got %v", value)
+    }
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+    "math"
+)
+
+/*
+   Growth model, mirroring state.State.Next: every turn the units of a player holding a node on their own
+   grow by GROWTH_FACTOR * units * (size - units) / size, rounded up, until they reach the node size.
+   This is logistic growth, so a node grows fastest when it is around half full (see FastestGrowth)
+   and stops growing once full.
+*/
+
+// how fast units multiply
+const GROWTH_FACTOR = 0.2
+
+// Grow returns the number of units on a node of the given size after one turn of growth
+func Grow(units, size int) int {
+    if units <= 0 || units >= size {
+        return units
+    }
+    return units + int(math.Ceil(GROWTH_FACTOR*float64(units)*float64(size-units)/float64(size)))
+}
+
+// GrowFor returns the number of units on a node of the given size after turns turns of growth
+func GrowFor(units, size, turns int) int {
+    for turn := 0; turn < turns && units > 0 && units < size; turn++ {
+        units = Grow(units, size)
+    }
+    return units
+}
+
+// TurnsToCapacity returns how many turns it takes until units on a node of the given size stop growing.
+// Returns 0 if the node is already full and UNREACHABLE if there are no units to grow.
+func TurnsToCapacity(units, size int) (result int) {
+    if units <= 0 {
+        return UNREACHABLE
+    }
+    for ; units < size; result++ {
+        units = Grow(units, size)
+    }
+    return
+}
+
+// MarginalGrowth returns how many more units a node of the given size will have gained after turns turns
+// if one extra unit is added to it now, not counting that extra unit itself
+func MarginalGrowth(units, size, turns int) int {
+    return GrowFor(units+1, size, turns) - GrowFor(units, size, turns) - 1
+}
+
+// FastestGrowth returns the smallest number of units at which a node of the given size grows the most in a
+// single turn. Keeping a node at this level and sending away the rest maximizes production.
+func FastestGrowth(size int) (result int) {
+    best := -1
+    for units := 1; units < size; units++ {
+        if growth := Grow(units, size) - units; growth > best {
+            best = growth
+            result = units
+        }
+    }
+    return
+}
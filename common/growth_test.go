@@ -0,0 +1,42 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "log"
+    "os"
+    "testing"
+)
+
+func TestGrowFor(t *testing.T) {
+    logger := log.New(os.Stdout, "", 0)
+    s := lineState(50, 1, 1)
+    s.Nodes["b"].Units["x"] = 3
+    for turn := 1; turn <= TurnsToCapacity(3, 50)+2; turn++ {
+        s.Next(logger, map[state.PlayerId]state.Orders{})
+        if expected := GrowFor(3, 50, turn); s.Nodes["b"].Units["x"] != expected {
+            t.Errorf("turn %v: expected %v units, state.Next gave %v", turn, expected, s.Nodes["b"].Units["x"])
+        }
+    }
+    if units := s.Nodes["b"].Units["x"]; units < 50 {
+        t.Errorf("expected b to be full after %v turns, got %v units", TurnsToCapacity(3, 50), units)
+    }
+}
+
+func TestGrowthHelpers(t *testing.T) {
+    if turns := TurnsToCapacity(50, 50); turns != 0 {
+        t.Errorf("expected a full node to need 0 turns, got %v", turns)
+    }
+    if turns := TurnsToCapacity(0, 50); turns != UNREACHABLE {
+        t.Errorf("expected an empty node to never fill up, got %v", turns)
+    }
+    if growth := MarginalGrowth(50, 50, 10); growth != 0 {
+        t.Errorf("expected no marginal growth on a full node, got %v", growth)
+    }
+    if growth := MarginalGrowth(0, 50, 10); growth <= 0 {
+        t.Errorf("expected marginal growth on an empty node, got %v", growth)
+    }
+    fastest := FastestGrowth(50)
+    if fastest > 25 || Grow(fastest, 50)-fastest < Grow(25, 50)-25 {
+        t.Errorf("expected %v units to grow at least as fast as a half full node", fastest)
+    }
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+)
+
+// find the edge leaving node towards dst
+func findEdge(node *state.Node, dst state.NodeId) (result *state.Edge) {
+    for _, edge := range node.Edges {
+        if edge.Dst == dst {
+            e := edge
+            return &e
+        }
+    }
+    return
+}
+
+/*
+InferOrders reconstructs the orders every player issued on the turn that turned previous into current.
+Each turn units on an edge move one step towards edge.Dst, so any units on an edge in current that can't be
+explained by moving the units in previous along must have just been sent from edge.Src.
+Orders are returned sorted by Src and Dst.
+
+Limitation: units sent along an edge of length 1 land on edge.Dst within the same turn and never show up on the
+edge, so those orders can't be inferred and are missing from the result. Working them out from the units on the
+nodes isn't possible in general, growth and fights on both ends mix them up with everything else.
+*/
+func InferOrders(previous, current *state.State) (result map[state.PlayerId]state.Orders) {
+    result = make(map[state.PlayerId]state.Orders)
+    for _, nodeId := range SortedNodeIds(current) {
+        previousNode, found := previous.Nodes[nodeId]
+        if !found {
+            continue
+        }
+        for _, edge := range SortedEdges(current.Nodes[nodeId]) {
+            previousEdge := findEdge(previousNode, edge.Dst)
+            if previousEdge == nil {
+                continue
+            }
+            sent := make(map[state.PlayerId]int)
+            for index, unitMap := range edge.Units {
+                for player, numUnits := range unitMap {
+                    // units that were one step behind last turn
+                    moved := 0
+                    if index > 0 && index-1 < len(previousEdge.Units) {
+                        moved = previousEdge.Units[index-1][player]
+                    }
+                    if numUnits > moved {
+                        sent[player] += numUnits - moved
+                    }
+                }
+            }
+            for _, player := range SortedPlayers(sent) {
+                result[player] = append(result[player], state.Order{
+                    Src:   edge.Src,
+                    Dst:   edge.Dst,
+                    Units: sent[player],
+                })
+            }
+        }
+    }
+    return
+}
+
+/*
+OrderHistory remembers the previous state of a single game, so that the orders of all players can be inferred
+each turn. Keep one OrderHistory per game and call Observe with every new state.
+*/
+type OrderHistory struct {
+    previous *state.State
+    // inferred orders of every turn observed so far, oldest first
+    Turns []map[state.PlayerId]state.Orders
+}
+
+// Observe records s and returns the orders inferred since the last observed state (nil on the first call)
+func (self *OrderHistory) Observe(s *state.State) (result map[state.PlayerId]state.Orders, err error) {
+    current, err := CopyState(s)
+    if err != nil {
+        return
+    }
+    if self.previous != nil {
+        result = InferOrders(self.previous, current)
+        self.Turns = append(self.Turns, result)
+    }
+    self.previous = current
+    return
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "log"
+    "math/rand"
+    "os"
+    "reflect"
+    "testing"
+)
+
+func TestInferOrders(t *testing.T) {
+    logger := log.New(os.Stdout, "", 0)
+    random := rand.New(rand.NewSource(1))
+    players := []state.PlayerId{"x", "y", "z"}
+
+    // a ring of nodes with edges of different lengths, all at least 2 turns (see TestInferOrdersShortEdges)
+    s := &state.State{Nodes: make(map[state.NodeId]*state.Node)}
+    ring := []state.NodeId{"a", "b", "c", "d", "e", "f"}
+    for _, id := range ring {
+        s.Nodes[id] = &state.Node{
+            Id:    id,
+            Size:  100,
+            Units: make(map[state.PlayerId]int),
+            Edges: make(map[state.NodeId]state.Edge),
+        }
+    }
+    for i, id := range ring {
+        connect(s, id, ring[(i+1)%len(ring)], 2+i%3)
+    }
+    s.Nodes["a"].Units["x"] = 40
+    s.Nodes["c"].Units["y"] = 40
+    s.Nodes["e"].Units["z"] = 40
+
+    history := &OrderHistory{}
+    history.Observe(s)
+    for turn := 0; turn < 20; turn++ {
+        // every player sends random numbers of units along random edges
+        orders := make(map[state.PlayerId]state.Orders)
+        for _, player := range players {
+            for _, nodeId := range SortedNodeIds(s) {
+                available := s.Nodes[nodeId].Units[player]
+                for _, edge := range SortedEdges(s.Nodes[nodeId]) {
+                    if available > 1 && random.Intn(2) == 0 {
+                        units := 1 + random.Intn(available-1)
+                        available -= units
+                        orders[player] = append(orders[player], state.Order{Src: nodeId, Dst: edge.Dst, Units: units})
+                    }
+                }
+            }
+        }
+        if s.Next(logger, orders) != nil {
+            break
+        }
+        inferred, err := history.Observe(s)
+        if err != nil {
+            t.Fatalf("observing state: %v", err)
+        }
+        for _, player := range players {
+            if len(orders[player]) == 0 && len(inferred[player]) == 0 {
+                continue
+            }
+            if !reflect.DeepEqual(orders[player], inferred[player]) {
+                t.Errorf("turn %v, player %v: sent %v but inferred %v", turn, player, orders[player], inferred[player])
+            }
+        }
+    }
+    if len(history.Turns) == 0 {
+        t.Errorf("expected the history to remember some turns")
+    }
+}
+
+func TestInferOrdersShortEdges(t *testing.T) {
+    logger := log.New(os.Stdout, "", 0)
+    // a - b is a single turn, b - c takes 2 turns
+    s := lineState(100, 1, 2)
+    s.Nodes["b"].Units["x"] = 20
+    previous, err := CopyState(s)
+    if err != nil {
+        t.Fatalf("copying state: %v", err)
+    }
+    s.Next(logger, map[state.PlayerId]state.Orders{
+        "x": state.Orders{
+            state.Order{Src: "b", Dst: "a", Units: 5},
+            state.Order{Src: "b", Dst: "c", Units: 5},
+        },
+    })
+    if s.Nodes["a"].Units["x"] <= 0 || len(s.Nodes["b"].Edges["a"].Units[0]) > 0 {
+        t.Fatalf("expected the units sent to a to have landed already, got %v", s.Nodes["a"].Units)
+    }
+    // only the order along the longer edge leaves a trace
+    expected := state.Orders{state.Order{Src: "b", Dst: "c", Units: 5}}
+    if inferred := InferOrders(previous, s)["x"]; !reflect.DeepEqual(inferred, expected) {
+        t.Errorf("expected to infer only %v, got %v", expected, inferred)
+    }
+}
@@ -0,0 +1,103 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+)
+
+/*
+   Influence describes how many units of each player could be standing on a node within a number of turns,
+   if every player sent everything they have straight there. Units on edges are included, they first have to
+   land on their edge.Dst before going anywhere else.
+
+   Reach[0] is what is on the node right now, Reach[t] is everything that could be there after t turns
+   (so it includes Reach[t-1]).
+*/
+type Influence struct {
+    Reach []map[state.PlayerId]int
+}
+
+// clamp turns to what was calculated
+func (self *Influence) turns(turns int) int {
+    if turns < 0 {
+        return 0
+    }
+    if turns >= len(self.Reach) {
+        return len(self.Reach) - 1
+    }
+    return turns
+}
+
+// Friendly returns how many of my units could be on the node within turns
+func (self *Influence) Friendly(me state.PlayerId, turns int) int {
+    return self.Reach[self.turns(turns)][me]
+}
+
+// Enemy returns how many enemy units (all players except me) could be on the node within turns
+func (self *Influence) Enemy(me state.PlayerId, turns int) (result int) {
+    for player, numUnits := range self.Reach[self.turns(turns)] {
+        if player != me {
+            result += numUnits
+        }
+    }
+    return
+}
+
+// Net returns friendly minus enemy units that could be on the node within turns, negative means danger
+func (self *Influence) Net(me state.PlayerId, turns int) int {
+    return self.Friendly(me, turns) - self.Enemy(me, turns)
+}
+
+// InfluenceMap holds the influence of every node in a state
+type InfluenceMap map[state.NodeId]*Influence
+
+// add numUnits of player to every node they can reach within horizon, starting at src in delay turns
+func (self InfluenceMap) spread(distances *DistanceMatrix, src state.NodeId, delay int, player state.PlayerId, numUnits int) {
+    for dst, influence := range self {
+        dist := distances.Distance(src, dst)
+        if dist == UNREACHABLE {
+            continue
+        }
+        if arrival := delay + dist; arrival < len(influence.Reach) {
+            influence.Reach[arrival][player] += numUnits
+        }
+    }
+}
+
+/*
+NewInfluenceMap calculates the influence of all players on every node in s, looking up to horizon turns ahead
+*/
+func NewInfluenceMap(s *state.State, distances *DistanceMatrix, horizon int) (result InfluenceMap) {
+    result = make(InfluenceMap, len(s.Nodes))
+    for nodeId := range s.Nodes {
+        influence := &Influence{Reach: make([]map[state.PlayerId]int, horizon+1)}
+        for turn := range influence.Reach {
+            influence.Reach[turn] = make(map[state.PlayerId]int)
+        }
+        result[nodeId] = influence
+    }
+    for nodeId, node := range s.Nodes {
+        for player, numUnits := range node.Units {
+            if numUnits > 0 {
+                result.spread(distances, nodeId, 0, player, numUnits)
+            }
+        }
+        for _, edge := range node.Edges {
+            for index, unitMap := range edge.Units {
+                for player, numUnits := range unitMap {
+                    if numUnits > 0 {
+                        result.spread(distances, edge.Dst, len(edge.Units)-index, player, numUnits)
+                    }
+                }
+            }
+        }
+    }
+    // make every turn include everything that could have arrived before it
+    for _, influence := range result {
+        for turn := 1; turn < len(influence.Reach); turn++ {
+            for player, numUnits := range influence.Reach[turn-1] {
+                influence.Reach[turn][player] += numUnits
+            }
+        }
+    }
+    return
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "testing"
+)
+
+func TestInfluenceMap(t *testing.T) {
+    s := lineState(30, 3, 2)
+    s.Nodes["a"].Units["x"] = 5
+    s.Nodes["c"].Units["y"] = 4
+    // 3 units of y on their way from c to b, landing in 2 turns
+    edge := s.Nodes["c"].Edges["b"]
+    edge.Units[0] = map[state.PlayerId]int{"y": 3}
+
+    influence := NewInfluenceMap(s, NewDistanceMatrix(s), 6)
+
+    b := influence["b"]
+    if friendly := b.Friendly("x", 2); friendly != 0 {
+        t.Errorf("expected no friendly units at b within 2 turns, got %v", friendly)
+    }
+    if friendly := b.Friendly("x", 3); friendly != 5 {
+        t.Errorf("expected 5 friendly units at b within 3 turns, got %v", friendly)
+    }
+    if enemy := b.Enemy("x", 2); enemy != 7 {
+        t.Errorf("expected 7 enemy units at b within 2 turns, got %v", enemy)
+    }
+    if net := b.Net("x", 100); net != -2 {
+        t.Errorf("expected net influence of -2 at b, got %v", net)
+    }
+    // the units on the edge have to land on b before they can go on to a
+    if enemy := influence["a"].Enemy("x", 4); enemy != 0 {
+        t.Errorf("expected no enemy units at a within 4 turns, got %v", enemy)
+    }
+    if enemy := influence["a"].Enemy("x", 5); enemy != 7 {
+        t.Errorf("expected 7 enemy units at a within 5 turns, got %v", enemy)
+    }
+}
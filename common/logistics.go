@@ -0,0 +1,112 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+)
+
+/*
+   Logistics splits the nodes owned by a player (the ones with their units on them) into
+   frontier nodes, which touch an enemy or unclaimed node (or have enemies on them), and interior nodes.
+   Owned nodes connected to each other through owned nodes form a region.
+   Every interior node also gets the next hop towards its nearest frontier node (preferring its own region),
+   which is where surplus units should be moved.
+*/
+type Logistics struct {
+    Frontier map[state.NodeId]bool
+    Interior map[state.NodeId]bool
+    Region   map[state.NodeId]int // index into Regions
+    Regions  [][]state.NodeId     // sorted node ids of each region
+    NextHop  map[state.NodeId]state.NodeId
+    Distance map[state.NodeId]int // turns from each interior node to its nearest frontier node
+}
+
+// IsOwned returns true if the node is either frontier or interior
+func (self *Logistics) IsOwned(nodeId state.NodeId) bool {
+    return self.Frontier[nodeId] || self.Interior[nodeId]
+}
+
+/*
+NewLogistics classifies all nodes owned by me in s
+*/
+func NewLogistics(me state.PlayerId, s *state.State, distances *DistanceMatrix) (result *Logistics) {
+    result = &Logistics{
+        Frontier: make(map[state.NodeId]bool),
+        Interior: make(map[state.NodeId]bool),
+        Region:   make(map[state.NodeId]int),
+        NextHop:  make(map[state.NodeId]state.NodeId),
+        Distance: make(map[state.NodeId]int),
+    }
+    nodeIds := SortedNodeIds(s)
+    owned := func(nodeId state.NodeId) bool {
+        return s.Nodes[nodeId].Units[me] > 0
+    }
+
+    // 1. frontier or interior
+    for _, nodeId := range nodeIds {
+        if !owned(nodeId) {
+            continue
+        }
+        frontier := CountNodeUnits(me, s.Nodes[nodeId]).EnemyUnits > 0
+        for _, edge := range s.Nodes[nodeId].Edges {
+            if !owned(edge.Dst) {
+                frontier = true
+            }
+        }
+        if frontier {
+            result.Frontier[nodeId] = true
+        } else {
+            result.Interior[nodeId] = true
+        }
+    }
+
+    // 2. regions, with a flood fill through owned nodes
+    for _, nodeId := range nodeIds {
+        if _, found := result.Region[nodeId]; found || !result.IsOwned(nodeId) {
+            continue
+        }
+        region := len(result.Regions)
+        members := []state.NodeId{}
+        queue := []state.NodeId{nodeId}
+        result.Region[nodeId] = region
+        for len(queue) > 0 {
+            current := queue[0]
+            queue = queue[1:]
+            members = append(members, current)
+            for _, edge := range SortedEdges(s.Nodes[current]) {
+                if _, found := result.Region[edge.Dst]; !found && result.IsOwned(edge.Dst) {
+                    result.Region[edge.Dst] = region
+                    queue = append(queue, edge.Dst)
+                }
+            }
+        }
+        SortNodeIds(members)
+        result.Regions = append(result.Regions, members)
+    }
+
+    // 3. route from every interior node to its nearest frontier, in its own region if possible
+    for nodeId := range result.Interior {
+        best := state.NodeId("")
+        bestDist := UNREACHABLE
+        bestSameRegion := false
+        for _, frontier := range nodeIds {
+            if !result.Frontier[frontier] {
+                continue
+            }
+            dist := distances.Distance(nodeId, frontier)
+            sameRegion := result.Region[frontier] == result.Region[nodeId]
+            if dist == UNREACHABLE || (bestSameRegion && !sameRegion) {
+                continue
+            }
+            if (sameRegion && !bestSameRegion) || dist < bestDist {
+                best = frontier
+                bestDist = dist
+                bestSameRegion = sameRegion
+            }
+        }
+        if bestDist != UNREACHABLE {
+            result.NextHop[nodeId] = distances.NextHop(nodeId, best)
+            result.Distance[nodeId] = bestDist
+        }
+    }
+    return
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+    "github.com/miridius/ai/aitest"
+    "testing"
+)
+
+func TestLogistics(t *testing.T) {
+    // a - b - c - d, x owns a, b and c, d is unclaimed
+    s := aitest.NewState(30, "a", "b", "c", "d")
+    aitest.Connect(s, "a", "b", 3)
+    aitest.Connect(s, "b", "c", 2)
+    aitest.Connect(s, "c", "d", 1)
+    s.Nodes["a"].Units["x"] = 5
+    s.Nodes["b"].Units["x"] = 5
+    s.Nodes["c"].Units["x"] = 5
+
+    logistics := NewLogistics("x", s, NewDistanceMatrix(s))
+
+    if !logistics.Frontier["c"] || len(logistics.Frontier) != 1 {
+        t.Errorf("expected only c to be frontier, got %v", logistics.Frontier)
+    }
+    if !logistics.Interior["a"] || !logistics.Interior["b"] || logistics.IsOwned("d") {
+        t.Errorf("expected a and b to be interior, got %v", logistics.Interior)
+    }
+    if len(logistics.Regions) != 1 || len(logistics.Regions[0]) != 3 {
+        t.Errorf("expected a single region of 3 nodes, got %v", logistics.Regions)
+    }
+    if logistics.NextHop["a"] != "b" || logistics.NextHop["b"] != "c" {
+        t.Errorf("expected a -> b -> c, got %v", logistics.NextHop)
+    }
+    if logistics.Distance["a"] != 5 {
+        t.Errorf("expected a to be 5 turns from the frontier, got %v", logistics.Distance["a"])
+    }
+}
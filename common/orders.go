@@ -0,0 +1,76 @@
+package common
+
+import (
+    "fmt"
+    "github.com/zond/stockholm-ai/state"
+)
+
+/*
+NormalizeOrders cleans up orders for player me before they are sent to the server:
+1. orders for 0 or less units, orders with Dst == Src and orders whose Dst is not adjacent to Src are dropped
+2. orders with the same Src and Dst are merged into one
+3. if more units are sent from a node than me has there, the last orders from that node are reduced (or dropped)
+The order of the remaining orders is kept. changes describes everything that was modified, for logging.
+*/
+func NormalizeOrders(me state.PlayerId, s *state.State, orders state.Orders) (result state.Orders, changes []string) {
+    // index of each Src/Dst pair in result
+    merged := make(map[[2]state.NodeId]int, len(orders))
+    for _, order := range orders {
+        if order.Units <= 0 {
+            changes = append(changes, fmt.Sprintf("dropped %+v: no units", order))
+            continue
+        }
+        if order.Src == order.Dst {
+            changes = append(changes, fmt.Sprintf("dropped %+v: Src and Dst are the same", order))
+            continue
+        }
+        node, found := s.Nodes[order.Src]
+        if !found {
+            changes = append(changes, fmt.Sprintf("dropped %+v: no such Src", order))
+            continue
+        }
+        adjacent := false
+        for _, edge := range node.Edges {
+            if edge.Dst == order.Dst {
+                adjacent = true
+                break
+            }
+        }
+        if !adjacent {
+            changes = append(changes, fmt.Sprintf("dropped %+v: Dst is not adjacent to Src", order))
+            continue
+        }
+        key := [2]state.NodeId{order.Src, order.Dst}
+        if index, found := merged[key]; found {
+            result[index].Units += order.Units
+            changes = append(changes, fmt.Sprintf("merged %+v into %+v", order, result[index]))
+            continue
+        }
+        merged[key] = len(result)
+        result = append(result, order)
+    }
+
+    // clamp overcommitted nodes
+    remaining := make(map[state.NodeId]int, len(result))
+    for _, order := range result {
+        if _, found := remaining[order.Src]; !found {
+            remaining[order.Src] = s.Nodes[order.Src].Units[me]
+        }
+    }
+    clamped := result[:0]
+    for _, order := range result {
+        available := remaining[order.Src]
+        if order.Units > available {
+            if available <= 0 {
+                changes = append(changes, fmt.Sprintf("dropped %+v: no units left on Src", order))
+                continue
+            }
+            changes = append(changes, fmt.Sprintf("reduced %+v to %v units", order, available))
+            order.Units = available
+        }
+        remaining[order.Src] -= order.Units
+        clamped = append(clamped, order)
+    }
+    result = clamped
+    return
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "testing"
+)
+
+func TestNormalizeOrders(t *testing.T) {
+    s := lineState(30, 3, 2)
+    s.Nodes["b"].Units["x"] = 10
+    s.Nodes["a"].Units["y"] = 5
+
+    result, changes := NormalizeOrders("x", s, state.Orders{
+        state.Order{Src: "b", Dst: "a", Units: 3},
+        state.Order{Src: "b", Dst: "b", Units: 1},
+        state.Order{Src: "b", Dst: "c", Units: 0},
+        state.Order{Src: "a", Dst: "c", Units: 2},
+        state.Order{Src: "b", Dst: "c", Units: 4},
+        state.Order{Src: "b", Dst: "a", Units: 2},
+        state.Order{Src: "b", Dst: "c", Units: 5},
+        state.Order{Src: "a", Dst: "b", Units: 1},
+    })
+    expected := state.Orders{
+        state.Order{Src: "b", Dst: "a", Units: 5},
+        state.Order{Src: "b", Dst: "c", Units: 5},
+    }
+    if len(result) != len(expected) {
+        t.Fatalf("expected %+v, got %+v (changes: %v)", expected, result, changes)
+    }
+    for i := range expected {
+        if result[i] != expected[i] {
+            t.Errorf("expected %+v, got %+v (changes: %v)", expected, result, changes)
+        }
+    }
+    if len(changes) != 7 {
+        t.Errorf("expected 7 changes, got %v", changes)
+    }
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+    "encoding/json"
+    "github.com/zond/stockholm-ai/state"
+    "io/ioutil"
+    "log"
+)
+
+// logger for states in simulations, nobody wants to read that output
+var quietLogger = log.New(ioutil.Discard, "", 0)
+
+// CopyState returns a deep copy of s, by round tripping it through JSON the same way the hub sends it to us
+func CopyState(s *state.State) (result *state.State, err error) {
+    data, err := json.Marshal(s)
+    if err != nil {
+        return
+    }
+    result = &state.State{}
+    err = json.Unmarshal(data, result)
+    return
+}
+
+// SimulationResult describes how a simulated game ended up
+type SimulationResult struct {
+    State     *state.State    // the state after the simulation, a copy that can be changed freely
+    Turns     int             // number of turns actually played
+    Winner    *state.PlayerId // set if only one player was left, in which case the simulation stops early
+    Standings StandingList    // standings at the end of the simulation
+}
+
+/*
+Simulate plays out turns on a copy of s without touching s itself.
+ordersByPlayer are executed on the first turn, after that nobody issues any orders.
+*/
+func Simulate(s *state.State, ordersByPlayer map[state.PlayerId]state.Orders, turns int) (result *SimulationResult, err error) {
+    sim, err := CopyState(s)
+    if err != nil {
+        return
+    }
+    result = &SimulationResult{State: sim}
+    orders := ordersByPlayer
+    for result.Turns < turns && result.Winner == nil {
+        result.Winner = sim.Next(quietLogger, orders)
+        result.Turns++
+        orders = map[state.PlayerId]state.Orders{}
+    }
+    result.Standings = Standings(sim)
+    return
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "reflect"
+    "testing"
+)
+
+func TestSimulate(t *testing.T) {
+    s := lineState(30, 3, 2)
+    s.Nodes["a"].Units["x"] = 20
+    s.Nodes["c"].Units["y"] = 4
+    before, err := CopyState(s)
+    if err != nil {
+        t.Fatalf("copying state: %v", err)
+    }
+    orders := map[state.PlayerId]state.Orders{
+        "x": state.Orders{state.Order{Src: "a", Dst: "b", Units: 15}},
+    }
+
+    result, err := Simulate(s, orders, 4)
+    if err != nil {
+        t.Fatalf("simulating: %v", err)
+    }
+    if !reflect.DeepEqual(s, before) {
+        t.Errorf("Simulate changed the real state")
+    }
+    if result.Turns != 4 || result.Winner != nil {
+        t.Errorf("expected 4 turns without a winner, got %v turns and winner %v", result.Turns, result.Winner)
+    }
+    if result.State.Nodes["b"].Units["x"] <= 0 {
+        t.Errorf("expected x to have landed on b, got %v", result.State.Nodes["b"].Units)
+    }
+
+    // playing the same turns on the real state gives the same result
+    s.Next(quietLogger, orders)
+    for turn := 1; turn < 4; turn++ {
+        s.Next(quietLogger, map[state.PlayerId]state.Orders{})
+    }
+    if !reflect.DeepEqual(Standings(s), result.Standings) {
+        t.Errorf("expected standings %+v, got %+v", Standings(s), result.Standings)
+    }
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "sort"
+)
+
+/*
+   Go randomizes map iteration order, so ranging over s.Nodes, node.Edges or node.Units gives
+   different orders (and therefore different results) for the same state on every run.
+   Range over these sorted slices instead whenever the order matters.
+*/
+
+// sortable slice of node ids
+type nodeIds []state.NodeId
+
+func (s nodeIds) Len() int           { return len(s) }
+func (s nodeIds) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s nodeIds) Less(i, j int) bool { return s[i] < s[j] }
+
+// sortable slice of player ids
+type playerIds []state.PlayerId
+
+func (s playerIds) Len() int           { return len(s) }
+func (s playerIds) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s playerIds) Less(i, j int) bool { return s[i] < s[j] }
+
+// sortable slice of edges, by destination
+type edgeList []state.Edge
+
+func (s edgeList) Len() int           { return len(s) }
+func (s edgeList) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s edgeList) Less(i, j int) bool { return s[i].Dst < s[j].Dst }
+
+// SortNodeIds sorts ids in place
+func SortNodeIds(ids []state.NodeId) {
+    sort.Sort(nodeIds(ids))
+}
+
+// SortedNodeIds returns the ids of all nodes in s, sorted
+func SortedNodeIds(s *state.State) (result []state.NodeId) {
+    result = make([]state.NodeId, 0, len(s.Nodes))
+    for nodeId := range s.Nodes {
+        result = append(result, nodeId)
+    }
+    SortNodeIds(result)
+    return
+}
+
+// SortedPlayers returns all players in units (e.g. node.Units), sorted
+func SortedPlayers(units map[state.PlayerId]int) (result []state.PlayerId) {
+    result = make([]state.PlayerId, 0, len(units))
+    for player := range units {
+        result = append(result, player)
+    }
+    sort.Sort(playerIds(result))
+    return
+}
+
+// SortedEdges returns all edges leaving node, sorted by destination
+func SortedEdges(node *state.Node) (result []state.Edge) {
+    result = make([]state.Edge, 0, len(node.Edges))
+    for _, edge := range node.Edges {
+        result = append(result, edge)
+    }
+    sort.Sort(edgeList(result))
+    return
+}
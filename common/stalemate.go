@@ -0,0 +1,116 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "math"
+    "sync"
+)
+
+const (
+    // how many turns nothing may happen before a game counts as a stalemate
+    STALEMATE_TURNS = 30
+    // how much (as a fraction) the units of a player may change and still count as stable
+    STALEMATE_TOLERANCE = 0.1
+    // but small armies may always change by this many units, they go up and down a lot with every fight
+    STALEMATE_UNITS = 10
+)
+
+/*
+StalemateDetector watches a single game for stalemates: no node changing owner and the unit totals of all
+players staying within STALEMATE_TOLERANCE for a number of turns. Keep one per game (and player) and call
+Observe every turn, it is safe to call from concurrent requests and ignores repeated calls for the same turn.
+*/
+type StalemateDetector struct {
+    mutex    sync.Mutex
+    turns    int
+    observed bool
+    lastTurn int
+    since    int                             // turn the current stable stretch started
+    owners   map[state.NodeId]state.PlayerId // last player seen holding each node
+    units    map[state.PlayerId]int          // unit totals at the start of the stable stretch
+}
+
+// NewStalemateDetector creates a detector that reports a stalemate after turns stable turns
+func NewStalemateDetector(turns int) *StalemateDetector {
+    return &StalemateDetector{turns: turns}
+}
+
+// the player holding each node, nodes without units (or with more than one player) are left out
+func owners(s *state.State) (result map[state.NodeId]state.PlayerId) {
+    result = make(map[state.NodeId]state.PlayerId, len(s.Nodes))
+    for nodeId, node := range s.Nodes {
+        players := 0
+        for player, numUnits := range node.Units {
+            if numUnits > 0 {
+                result[nodeId] = player
+                players++
+            }
+        }
+        if players > 1 {
+            delete(result, nodeId)
+        }
+    }
+    return
+}
+
+// the total units of every player in s
+func unitTotals(s *state.State) (result map[state.PlayerId]int) {
+    result = make(map[state.PlayerId]int)
+    for _, standing := range Standings(s) {
+        result[standing.Player] = standing.Units()
+    }
+    return
+}
+
+/*
+updates known with the current owners, and returns true if any node was taken by a new player.
+Nodes that are emptied (and usually retaken by the same player) don't count, in a stalemate that happens all the time.
+*/
+func updateOwners(known, current map[state.NodeId]state.PlayerId) (changed bool) {
+    for nodeId, player := range current {
+        if known[nodeId] != player {
+            changed = true
+            known[nodeId] = player
+        }
+    }
+    return
+}
+
+// true if no player in units has gained or lost more than STALEMATE_TOLERANCE (or STALEMATE_UNITS) since start
+func stableUnits(start, units map[state.PlayerId]int) bool {
+    if len(start) != len(units) {
+        return false
+    }
+    for player, numUnits := range units {
+        allowed := math.Max(STALEMATE_TOLERANCE*float64(start[player]), STALEMATE_UNITS)
+        if diff := numUnits - start[player]; float64(diff) > allowed || float64(-diff) > allowed {
+            return false
+        }
+    }
+    return true
+}
+
+/*
+Observe records s and returns the number of turns nothing has happened and whether that makes it a stalemate.
+A turn number lower than the last one observed means a new game on the same map, which starts over.
+*/
+func (self *StalemateDetector) Observe(s *state.State) (stable int, stalemate bool) {
+    self.mutex.Lock()
+    defer self.mutex.Unlock()
+    if !self.observed || s.Turn < self.lastTurn {
+        self.observed = true
+        self.lastTurn = s.Turn
+        self.since = s.Turn
+        self.owners = owners(s)
+        self.units = unitTotals(s)
+    } else if s.Turn > self.lastTurn {
+        self.lastTurn = s.Turn
+        currentUnits := unitTotals(s)
+        if changed := updateOwners(self.owners, owners(s)); changed || !stableUnits(self.units, currentUnits) {
+            self.since = s.Turn
+            self.units = currentUnits
+        }
+    }
+    stable = self.lastTurn - self.since
+    return stable, stable >= self.turns
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "testing"
+)
+
+func TestStalemateDetector(t *testing.T) {
+    s := lineState(100, 1, 1)
+    s.Nodes["a"].Units["x"] = 20
+    s.Nodes["b"].Units["x"] = 5
+    s.Nodes["c"].Units["y"] = 20
+    detector := NewStalemateDetector(5)
+
+    // nothing happening for 5 turns is a stalemate, asking twice in the same turn doesn't count
+    for turn := 0; turn <= 5; turn++ {
+        s.Turn = turn
+        detector.Observe(s)
+        if stable, stalemate := detector.Observe(s); stable != turn || stalemate != (turn == 5) {
+            t.Errorf("turn %v: expected %v stable turns (stalemate %v), got %v (%v)", turn, turn, turn == 5, stable, stalemate)
+        }
+    }
+
+    // units going up and down a bit, and nodes being emptied, don't change anything
+    s.Turn++
+    s.Nodes["a"].Units["x"] = 25
+    delete(s.Nodes["b"].Units, "x")
+    if stable, stalemate := detector.Observe(s); stable != 6 || !stalemate {
+        t.Errorf("expected small changes to keep the stalemate, got %v stable turns (%v)", stable, stalemate)
+    }
+    s.Turn++
+    s.Nodes["b"].Units["x"] = 3
+    if stable, _ := detector.Observe(s); stable != 7 {
+        t.Errorf("expected x retaking b to keep the stalemate, got %v stable turns", stable)
+    }
+
+    // a node changing hands starts over
+    s.Turn++
+    s.Nodes["b"].Units = state.Units{"y": 1}
+    if stable, stalemate := detector.Observe(s); stable != 0 || stalemate {
+        t.Errorf("expected y taking b to end the stalemate, got %v stable turns (%v)", stable, stalemate)
+    }
+
+    // and so does a player growing
+    s.Turn++
+    if stable, _ := detector.Observe(s); stable != 1 {
+        t.Errorf("expected 1 stable turn, got %v", stable)
+    }
+    s.Turn++
+    s.Nodes["a"].Units["x"] = 50
+    if stable, _ := detector.Observe(s); stable != 0 {
+        t.Errorf("expected x doubling its units to end the stable stretch, got %v stable turns", stable)
+    }
+
+    // a new game on the same map
+    s.Turn = 0
+    s.Nodes["a"].Units = state.Units{"y": 1}
+    if stable, _ := detector.Observe(s); stable != 0 {
+        t.Errorf("expected a new game to start over, got %v stable turns", stable)
+    }
+}
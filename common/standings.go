@@ -0,0 +1,93 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "sort"
+)
+
+// PlayerStanding summarizes how well a single player is doing
+type PlayerStanding struct {
+    Player       state.PlayerId
+    Nodes        int // number of nodes held
+    Size         int // total size of all nodes held
+    NodeUnits    int // units standing on nodes
+    TransitUnits int // units on edges
+    Growth       int // units the player will gain from growth next turn
+}
+
+// Units returns all units of the player, both on nodes and edges
+func (self PlayerStanding) Units() int {
+    return self.NodeUnits + self.TransitUnits
+}
+
+// define a sortable list of standings
+type StandingList []PlayerStanding
+
+func (s StandingList) Len() int      { return len(s) }
+func (s StandingList) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// sort a StandingList strongest first: most units, then highest growth, then most size held
+type ByStrength struct{ StandingList }
+
+func (s ByStrength) Less(i, j int) bool {
+    a, b := s.StandingList[i], s.StandingList[j]
+    if a.Units() != b.Units() {
+        return a.Units() > b.Units()
+    }
+    if a.Growth != b.Growth {
+        return a.Growth > b.Growth
+    }
+    if a.Size != b.Size {
+        return a.Size > b.Size
+    }
+    return a.Player < b.Player
+}
+
+// Get returns the standing of player, and false if the player has nothing left
+func (self StandingList) Get(player state.PlayerId) (PlayerStanding, bool) {
+    for _, standing := range self {
+        if standing.Player == player {
+            return standing, true
+        }
+    }
+    return PlayerStanding{Player: player}, false
+}
+
+/*
+Standings returns a snapshot of every player that still has units in s, strongest first
+*/
+func Standings(s *state.State) (result StandingList) {
+    byPlayer := make(map[state.PlayerId]*PlayerStanding)
+    get := func(player state.PlayerId) *PlayerStanding {
+        if byPlayer[player] == nil {
+            byPlayer[player] = &PlayerStanding{Player: player}
+        }
+        return byPlayer[player]
+    }
+    for _, node := range s.Nodes {
+        for player, numUnits := range node.Units {
+            if numUnits > 0 {
+                standing := get(player)
+                standing.Nodes++
+                standing.Size += node.Size
+                standing.NodeUnits += numUnits
+                standing.Growth += Grow(numUnits, node.Size) - numUnits
+            }
+        }
+        for _, edge := range node.Edges {
+            for _, unitMap := range edge.Units {
+                for player, numUnits := range unitMap {
+                    if numUnits > 0 {
+                        get(player).TransitUnits += numUnits
+                    }
+                }
+            }
+        }
+    }
+    result = make(StandingList, 0, len(byPlayer))
+    for _, standing := range byPlayer {
+        result = append(result, *standing)
+    }
+    sort.Sort(ByStrength{result})
+    return
+}
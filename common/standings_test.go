@@ -0,0 +1,31 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "testing"
+)
+
+func TestStandings(t *testing.T) {
+    s := lineState(30, 3, 2)
+    s.Nodes["a"].Units["x"] = 5
+    s.Nodes["b"].Units["y"] = 4
+    s.Nodes["c"].Units["y"] = 4
+    edge := s.Nodes["a"].Edges["b"]
+    edge.Units[0] = map[state.PlayerId]int{"x": 6}
+
+    standings := Standings(s)
+    if len(standings) != 2 || standings[0].Player != "x" || standings[1].Player != "y" {
+        t.Fatalf("expected x ahead of y, got %+v", standings)
+    }
+    x, _ := standings.Get("x")
+    if x.Nodes != 1 || x.Size != 30 || x.NodeUnits != 5 || x.TransitUnits != 6 || x.Units() != 11 {
+        t.Errorf("unexpected standing for x: %+v", x)
+    }
+    y, _ := standings.Get("y")
+    if y.Nodes != 2 || y.Size != 60 || y.Growth != 2*(Grow(4, 30)-4) {
+        t.Errorf("unexpected standing for y: %+v", y)
+    }
+    if _, found := standings.Get("z"); found {
+        t.Errorf("expected z to have no standing")
+    }
+}
@@ -0,0 +1,188 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "sort"
+)
+
+/*
+   Structure describes the static shape of the map, which never changes during a game,
+   so it only needs to be calculated once per map (see NewStructure).
+
+   Articulation points are nodes whose loss splits the map in two, bridges are edges whose loss does the same.
+   Betweenness is the (unnormalized) number of shortest paths between other nodes that go through a node,
+   weighted by edge length.
+*/
+type Structure struct {
+    Degree       map[state.NodeId]int
+    Betweenness  map[state.NodeId]float64
+    Articulation map[state.NodeId]bool
+    Bridges      []Bridge
+}
+
+// Bridge is an edge (stored as Src < Dst) that is the only connection between two parts of the map
+type Bridge struct {
+    Src, Dst state.NodeId
+    // number of nodes on each side of the bridge
+    SrcSide, DstSide int
+}
+
+// IsBridge returns true if the edge between a and b (in either direction) is a bridge
+func (self *Structure) IsBridge(a, b state.NodeId) bool {
+    for _, bridge := range self.Bridges {
+        if (bridge.Src == a && bridge.Dst == b) || (bridge.Src == b && bridge.Dst == a) {
+            return true
+        }
+    }
+    return false
+}
+
+// neighbours of every node, sorted, treating the map as undirected
+func neighbours(s *state.State) (result map[state.NodeId][]state.NodeId) {
+    result = make(map[state.NodeId][]state.NodeId, len(s.Nodes))
+    seen := make(map[[2]state.NodeId]bool)
+    for nodeId, node := range s.Nodes {
+        for _, edge := range node.Edges {
+            if _, found := s.Nodes[edge.Dst]; !found {
+                continue
+            }
+            for _, key := range [][2]state.NodeId{{nodeId, edge.Dst}, {edge.Dst, nodeId}} {
+                if !seen[key] {
+                    seen[key] = true
+                    result[key[0]] = append(result[key[0]], key[1])
+                }
+            }
+        }
+    }
+    for nodeId := range result {
+        SortNodeIds(result[nodeId])
+    }
+    return
+}
+
+/*
+NewStructure analyses the graph of s. distances must be built from the same map.
+*/
+func NewStructure(s *state.State, distances *DistanceMatrix) (result *Structure) {
+    result = &Structure{
+        Degree:       make(map[state.NodeId]int, len(s.Nodes)),
+        Betweenness:  make(map[state.NodeId]float64, len(s.Nodes)),
+        Articulation: make(map[state.NodeId]bool),
+    }
+    links := neighbours(s)
+    ids := SortedNodeIds(s)
+    for _, nodeId := range ids {
+        result.Degree[nodeId] = len(links[nodeId])
+    }
+
+    // 1. articulation points and bridges with tarjan's algorithm
+    order := make(map[state.NodeId]int, len(ids)) // discovery order, starting at 1
+    low := make(map[state.NodeId]int, len(ids))
+    size := make(map[state.NodeId]int, len(ids)) // size of the dfs subtree
+    counter := 0
+    var visit func(nodeId, parent state.NodeId, root bool) int
+    visit = func(nodeId, parent state.NodeId, root bool) int {
+        counter++
+        order[nodeId] = counter
+        low[nodeId] = counter
+        size[nodeId] = 1
+        children := 0
+        for _, next := range links[nodeId] {
+            if next == parent {
+                continue
+            }
+            if order[next] == 0 {
+                children++
+                visit(next, nodeId, false)
+                size[nodeId] += size[next]
+                low[nodeId] = Min(low[nodeId], low[next])
+                if !root && low[next] >= order[nodeId] {
+                    result.Articulation[nodeId] = true
+                }
+                if low[next] > order[nodeId] {
+                    result.Bridges = append(result.Bridges, Bridge{Src: nodeId, Dst: next, DstSide: size[next]})
+                }
+            } else {
+                low[nodeId] = Min(low[nodeId], order[next])
+            }
+        }
+        if root && children > 1 {
+            result.Articulation[nodeId] = true
+        }
+        return size[nodeId]
+    }
+    for _, nodeId := range ids {
+        if order[nodeId] == 0 {
+            componentSize := visit(nodeId, nodeId, true)
+            // now that the component size is known, fill in the other side of its bridges
+            for i := range result.Bridges {
+                if result.Bridges[i].SrcSide == 0 {
+                    result.Bridges[i].SrcSide = componentSize - result.Bridges[i].DstSide
+                }
+            }
+        }
+    }
+    for i, bridge := range result.Bridges {
+        if bridge.Dst < bridge.Src {
+            result.Bridges[i] = Bridge{bridge.Dst, bridge.Src, bridge.DstSide, bridge.SrcSide}
+        }
+    }
+
+    // 2. betweenness: node v is on a shortest path from a to b if dist(a, v) + dist(v, b) == dist(a, b).
+    // count what share of the shortest paths between a and b go through v.
+    paths := make(map[state.NodeId]map[state.NodeId]float64, len(ids)) // number of shortest paths
+    for _, src := range ids {
+        paths[src] = countShortestPaths(s, distances, ids, src)
+    }
+    for _, v := range ids {
+        for _, a := range ids {
+            if a == v {
+                continue
+            }
+            for _, b := range ids {
+                if b == v || b == a || paths[a][b] == 0 {
+                    continue
+                }
+                if distances.Distance(a, v)+distances.Distance(v, b) == distances.Distance(a, b) {
+                    result.Betweenness[v] += paths[a][v] * paths[v][b] / paths[a][b]
+                }
+            }
+        }
+    }
+    return
+}
+
+// number of different shortest paths from src to every node
+func countShortestPaths(s *state.State, distances *DistanceMatrix, ids []state.NodeId, src state.NodeId) (result map[state.NodeId]float64) {
+    result = make(map[state.NodeId]float64, len(ids))
+    // visit nodes closest first, so that every node's predecessors are counted before it
+    sorted := make([]state.NodeId, 0, len(ids))
+    for _, nodeId := range ids {
+        if distances.Distance(src, nodeId) != UNREACHABLE {
+            sorted = append(sorted, nodeId)
+        }
+    }
+    sort.Stable(byDistance{sorted, distances, src})
+    result[src] = 1
+    for _, nodeId := range sorted {
+        for _, edge := range s.Nodes[nodeId].Edges {
+            if distances.Distance(src, nodeId)+len(edge.Units) == distances.Distance(src, edge.Dst) {
+                result[edge.Dst] += result[nodeId]
+            }
+        }
+    }
+    return
+}
+
+// sort node ids by distance from a source node
+type byDistance struct {
+    ids       []state.NodeId
+    distances *DistanceMatrix
+    src       state.NodeId
+}
+
+func (s byDistance) Len() int      { return len(s.ids) }
+func (s byDistance) Swap(i, j int) { s.ids[i], s.ids[j] = s.ids[j], s.ids[i] }
+func (s byDistance) Less(i, j int) bool {
+    return s.distances.Distance(s.src, s.ids[i]) < s.distances.Distance(s.src, s.ids[j])
+}
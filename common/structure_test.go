@@ -0,0 +1,37 @@
+package common
+
+import (
+    "testing"
+)
+
+func TestStructure(t *testing.T) {
+    // a - b - c is a line, so b is a chokepoint and both edges are bridges
+    s := lineState(30, 3, 2)
+    structure := NewStructure(s, NewDistanceMatrix(s))
+
+    if !structure.Articulation["b"] || structure.Articulation["a"] || structure.Articulation["c"] {
+        t.Errorf("expected only b to be an articulation point, got %v", structure.Articulation)
+    }
+    if len(structure.Bridges) != 2 || !structure.IsBridge("a", "b") || !structure.IsBridge("c", "b") {
+        t.Errorf("expected a-b and b-c to be bridges, got %+v", structure.Bridges)
+    }
+    for _, bridge := range structure.Bridges {
+        if bridge.SrcSide+bridge.DstSide != 3 {
+            t.Errorf("expected both sides of %+v to add up to 3 nodes", bridge)
+        }
+    }
+    if structure.Degree["b"] != 2 || structure.Degree["a"] != 1 {
+        t.Errorf("unexpected degrees %v", structure.Degree)
+    }
+    // only the paths a -> c and c -> a go through b
+    if structure.Betweenness["b"] != 2 || structure.Betweenness["a"] != 0 {
+        t.Errorf("unexpected betweenness %v", structure.Betweenness)
+    }
+
+    // closing the loop removes all chokepoints
+    connect(s, "a", "c", 4)
+    structure = NewStructure(s, NewDistanceMatrix(s))
+    if len(structure.Articulation) != 0 || len(structure.Bridges) != 0 {
+        t.Errorf("expected no chokepoints in a loop, got %v and %+v", structure.Articulation, structure.Bridges)
+    }
+}
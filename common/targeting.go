@@ -0,0 +1,115 @@
+package common
+
+import (
+    "fmt"
+    "github.com/zond/stockholm-ai/state"
+    "sort"
+)
+
+const (
+    // how many turns away a player's units may be from my nodes for them to count as a neighbour
+    NEIGHBOUR_TURNS = 5
+    // a leader with this many times the units of the runner up is running away with the game
+    RUNAWAY_RATIO = 1.5
+)
+
+// TargetPlayer is an opponent recommended for attack, with a score (higher is more urgent) and why
+type TargetPlayer struct {
+    Player  state.PlayerId
+    Score   float64
+    Reasons []string
+}
+
+// define a sortable list of targets
+type TargetList []TargetPlayer
+
+func (s TargetList) Len() int      { return len(s) }
+func (s TargetList) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// sort a TargetList by score, highest first
+type ByScore struct{ TargetList }
+
+func (s ByScore) Less(i, j int) bool {
+    if s.TargetList[i].Score != s.TargetList[j].Score {
+        return s.TargetList[i].Score > s.TargetList[j].Score
+    }
+    return s.TargetList[i].Player < s.TargetList[j].Player
+}
+
+/*
+RankTargets recommends which opponents to attack, most urgent first. Each opponent scores for:
+1. attacking us: their units on edges heading to my nodes, relative to my units
+2. being the weakest neighbour: my units relative to theirs, if they can reach my nodes within NEIGHBOUR_TURNS
+3. running away with the game: their units relative to the runner up, if they lead by RUNAWAY_RATIO
+Opponents scoring nothing are still listed (with score 0) so that every living opponent is ranked.
+*/
+func RankTargets(me state.PlayerId, s *state.State, standings StandingList, influence InfluenceMap) (result TargetList) {
+    mine, _ := standings.Get(me)
+    myUnits := float64(mine.Units())
+    if myUnits < 1 {
+        myUnits = 1
+    }
+
+    // 1. units of each opponent heading to nodes I hold
+    attacking := make(map[state.PlayerId]int)
+    for _, node := range s.Nodes {
+        for _, edge := range node.Edges {
+            if s.Nodes[edge.Dst] == nil || s.Nodes[edge.Dst].Units[me] <= 0 {
+                continue
+            }
+            for _, unitMap := range edge.Units {
+                for player, numUnits := range unitMap {
+                    if player != me {
+                        attacking[player] += numUnits
+                    }
+                }
+            }
+        }
+    }
+
+    // 2. opponents that can reach my nodes soon
+    neighbours := make(map[state.PlayerId]bool)
+    for nodeId, nodeInfluence := range influence {
+        if s.Nodes[nodeId].Units[me] <= 0 {
+            continue
+        }
+        for player, numUnits := range nodeInfluence.Reach[nodeInfluence.turns(NEIGHBOUR_TURNS)] {
+            if player != me && numUnits > 0 {
+                neighbours[player] = true
+            }
+        }
+    }
+    weakest := state.PlayerId("")
+    weakestUnits := -1
+    for _, standing := range standings {
+        if neighbours[standing.Player] && (weakestUnits < 0 || standing.Units() < weakestUnits) {
+            weakest = standing.Player
+            weakestUnits = standing.Units()
+        }
+    }
+
+    for index, standing := range standings {
+        if standing.Player == me {
+            continue
+        }
+        target := TargetPlayer{Player: standing.Player}
+        if units := attacking[standing.Player]; units > 0 {
+            target.Score += float64(units) / myUnits
+            target.Reasons = append(target.Reasons, fmt.Sprintf("attacking us with %v units", units))
+        }
+        if standing.Player == weakest {
+            target.Score += myUnits / float64(Max(standing.Units(), 1))
+            target.Reasons = append(target.Reasons, fmt.Sprintf("weakest neighbour with %v units", standing.Units()))
+        }
+        if index == 0 && len(standings) > 1 {
+            runnerUp := float64(Max(standings[1].Units(), 1))
+            if ratio := float64(standing.Units()) / runnerUp; ratio >= RUNAWAY_RATIO {
+                target.Score += ratio
+                target.Reasons = append(target.Reasons, fmt.Sprintf("runaway leader with %.1fx the units of the runner up", ratio))
+            }
+        }
+        result = append(result, target)
+    }
+    sort.Sort(ByScore{result})
+    return
+}
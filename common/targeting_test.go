@@ -0,0 +1,31 @@
+package common
+
+import (
+    "github.com/miridius/ai/aitest"
+    "testing"
+)
+
+func TestRankTargets(t *testing.T) {
+    // x holds b in the middle, y attacks it from a, z sits quietly far away on twice our army
+    s := aitest.NewState(30, "a", "b", "c", "d")
+    aitest.Connect(s, "a", "b", 1)
+    aitest.Connect(s, "b", "c", 1)
+    aitest.Connect(s, "c", "d", 20)
+    s.Nodes["b"].Units["x"] = 20
+    s.Nodes["a"].Units["y"] = 3
+    aitest.Send(s, "a", "b", "y", 5)
+    s.Nodes["d"].Units["z"] = 40
+
+    standings := Standings(s)
+    targets := RankTargets("x", s, standings, NewInfluenceMap(s, NewDistanceMatrix(s), 10))
+
+    if len(targets) != 2 {
+        t.Fatalf("expected y and z to be ranked, got %+v", targets)
+    }
+    if targets[0].Player != "y" || len(targets[0].Reasons) != 2 {
+        t.Errorf("expected y first, for attacking us and being the weakest neighbour, got %+v", targets)
+    }
+    if targets[1].Player != "z" || len(targets[1].Reasons) != 1 {
+        t.Errorf("expected z second, as the runaway leader, got %+v", targets)
+    }
+}
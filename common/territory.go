@@ -0,0 +1,90 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+)
+
+/*
+   TerritoryInfo describes which player can get units to a node first.
+   Owner is empty if nobody can reach the node, or if several players tie for first (then Margin is 0).
+*/
+type TerritoryInfo struct {
+    Owner   state.PlayerId
+    Arrival int // turns until the first units of Owner arrive, 0 if they are already there
+    Margin  int // turns between Owner's arrival and the next player's, UNREACHABLE if nobody else can get there
+    Units   int // number of Owner's units arriving first
+}
+
+// earliest arrival of a single player at a node
+type arrival struct {
+    turn, units int
+}
+
+/*
+Territory assigns each node in s to the player who can get units there first, using all units on nodes and edges.
+This is a voronoi partition of the map weighted by travel time.
+*/
+func Territory(s *state.State, distances *DistanceMatrix) (result map[state.NodeId]TerritoryInfo) {
+    // earliest arrival of each player at each node
+    arrivals := make(map[state.NodeId]map[state.PlayerId]arrival, len(s.Nodes))
+    for nodeId := range s.Nodes {
+        arrivals[nodeId] = make(map[state.PlayerId]arrival)
+    }
+    spread := func(src state.NodeId, delay int, player state.PlayerId, numUnits int) {
+        for dst, players := range arrivals {
+            dist := distances.Distance(src, dst)
+            if dist == UNREACHABLE {
+                continue
+            }
+            turn := delay + dist
+            if current, found := players[player]; !found || turn < current.turn {
+                players[player] = arrival{turn, numUnits}
+            } else if turn == current.turn {
+                players[player] = arrival{turn, current.units + numUnits}
+            }
+        }
+    }
+    for nodeId, node := range s.Nodes {
+        for player, numUnits := range node.Units {
+            if numUnits > 0 {
+                spread(nodeId, 0, player, numUnits)
+            }
+        }
+        for _, edge := range node.Edges {
+            for index, unitMap := range edge.Units {
+                for player, numUnits := range unitMap {
+                    if numUnits > 0 {
+                        spread(edge.Dst, len(edge.Units)-index, player, numUnits)
+                    }
+                }
+            }
+        }
+    }
+
+    result = make(map[state.NodeId]TerritoryInfo, len(s.Nodes))
+    for nodeId, players := range arrivals {
+        info := TerritoryInfo{Arrival: UNREACHABLE, Margin: UNREACHABLE}
+        for player, a := range players {
+            if a.turn < info.Arrival {
+                // new leader, the old leader (if any) is now the runner up
+                if info.Arrival != UNREACHABLE {
+                    info.Margin = info.Arrival - a.turn
+                }
+                info.Owner = player
+                info.Arrival = a.turn
+                info.Units = a.units
+            } else if a.turn == info.Arrival {
+                // tie for first place
+                info.Margin = 0
+            } else if a.turn-info.Arrival < info.Margin {
+                info.Margin = a.turn - info.Arrival
+            }
+        }
+        if info.Margin == 0 {
+            info.Owner = ""
+            info.Units = 0
+        }
+        result[nodeId] = info
+    }
+    return
+}
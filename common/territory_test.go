@@ -0,0 +1,29 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "testing"
+)
+
+func TestTerritory(t *testing.T) {
+    s := lineState(30, 3, 2)
+    s.Nodes["a"].Units["x"] = 5
+    s.Nodes["c"].Units["y"] = 4
+
+    territory := Territory(s, NewDistanceMatrix(s))
+
+    if info := territory["a"]; info.Owner != "x" || info.Arrival != 0 || info.Margin != 5 || info.Units != 5 {
+        t.Errorf("expected x to hold a with a margin of 5, got %+v", info)
+    }
+    if info := territory["b"]; info.Owner != "y" || info.Arrival != 2 || info.Margin != 1 || info.Units != 4 {
+        t.Errorf("expected y to reach b first by 1 turn, got %+v", info)
+    }
+
+    // once x has units on their way to b it becomes a tie
+    edge := s.Nodes["a"].Edges["b"]
+    edge.Units[1] = map[state.PlayerId]int{"x": 2}
+    territory = Territory(s, NewDistanceMatrix(s))
+    if info := territory["b"]; info.Owner != "" || info.Margin != 0 {
+        t.Errorf("expected b to be contested, got %+v", info)
+    }
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "sort"
+)
+
+/*
+   Timeline describes units approaching a node, keyed by how many turns until they land.
+   timeline[3]["a"] == 5 means player a has 5 units arriving in 3 turns.
+   Units already standing on the node are not part of the timeline.
+*/
+type Timeline map[int]map[state.PlayerId]int
+
+// add numUnits for player arriving in delay turns
+func (self Timeline) addUnits(delay int, player state.PlayerId, numUnits int) {
+    if numUnits <= 0 {
+        return
+    }
+    if self[delay] == nil {
+        self[delay] = make(map[state.PlayerId]int)
+    }
+    self[delay][player] += numUnits
+}
+
+// merge all arrivals in t into self
+func (self Timeline) merge(t Timeline) {
+    for delay, unitMap := range t {
+        for player, numUnits := range unitMap {
+            self.addUnits(delay, player, numUnits)
+        }
+    }
+}
+
+// Turns returns every delay at which some units arrive, soonest first
+func (self Timeline) Turns() (result []int) {
+    result = make([]int, 0, len(self))
+    for delay := range self {
+        result = append(result, delay)
+    }
+    sort.Ints(result)
+    return
+}
+
+// Friendly returns the number of my units arriving in exactly delay turns
+func (self Timeline) Friendly(me state.PlayerId, delay int) int {
+    return self[delay][me]
+}
+
+// Enemy returns the number of enemy units (all players except me) arriving in exactly delay turns
+func (self Timeline) Enemy(me state.PlayerId, delay int) (result int) {
+    for player, numUnits := range self[delay] {
+        if player != me {
+            result += numUnits
+        }
+    }
+    return
+}
+
+// FirstEnemy returns the delay of the first enemy arrival, or DELAY_NO_UNITS if no enemies are coming
+func (self Timeline) FirstEnemy(me state.PlayerId) int {
+    for _, delay := range self.Turns() {
+        if self.Enemy(me, delay) > 0 {
+            return delay
+        }
+    }
+    return DELAY_NO_UNITS
+}
+
+// FirstFriendly returns the delay of my first arrival, or DELAY_NO_UNITS if none of my units are coming
+func (self Timeline) FirstFriendly(me state.PlayerId) int {
+    for _, delay := range self.Turns() {
+        if self.Friendly(me, delay) > 0 {
+            return delay
+        }
+    }
+    return DELAY_NO_UNITS
+}
+
+/*
+FirstToClaim returns true if units sent now, landing in delay turns, would be the first to get to node:
+nobody holds it and neither my units nor enemy units land on it in delay turns or sooner
+*/
+func FirstToClaim(me state.PlayerId, node *state.Node, arrivals Timeline, delay int) bool {
+    for _, numUnits := range node.Units {
+        if numUnits > 0 {
+            return false
+        }
+    }
+    return Min(arrivals.FirstEnemy(me), arrivals.FirstFriendly(me)) > delay
+}
+
+// EdgeTimeline builds the timeline of all units on edge, as seen from edge.Dst
+func EdgeTimeline(edge *state.Edge) (result Timeline) {
+    result = make(Timeline)
+    for index, unitMap := range edge.Units {
+        for player, numUnits := range unitMap {
+            result.addUnits(len(edge.Units)-index, player, numUnits)
+        }
+    }
+    return
+}
@@ -0,0 +1,57 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "reflect"
+    "testing"
+)
+
+func TestTimeline(t *testing.T) {
+    // a - b - c, with x and y on their way to b from both sides
+    s := lineState(30, 3, 2)
+    s.Nodes["a"].Edges["b"].Units[0] = state.Units{"x": 4}
+    s.Nodes["a"].Edges["b"].Units[2] = state.Units{"y": 2}
+    s.Nodes["c"].Edges["b"].Units[0] = state.Units{"x": 1, "y": 3}
+
+    arrivals := CountAllUnits("x", s)["b"].Arrivals
+    if turns := arrivals.Turns(); !reflect.DeepEqual(turns, []int{1, 2, 3}) {
+        t.Errorf("expected arrivals in 1, 2 and 3 turns, got %v", turns)
+    }
+    if arrivals.Enemy("x", 1) != 2 || arrivals.Enemy("x", 2) != 3 || arrivals.Friendly("x", 2) != 1 || arrivals.Friendly("x", 3) != 4 {
+        t.Errorf("expected y: 2 in 1 turn, 3 in 2 turns and x: 1 in 2 turns, 4 in 3 turns, got %v", arrivals)
+    }
+    if first := arrivals.FirstEnemy("x"); first != 1 {
+        t.Errorf("expected the first enemy in 1 turn, got %v", first)
+    }
+    if first := arrivals.FirstFriendly("x"); first != 2 {
+        t.Errorf("expected my first units in 2 turns, got %v", first)
+    }
+    if first := CountAllUnits("x", s)["a"].Arrivals.FirstEnemy("x"); first != DELAY_NO_UNITS {
+        t.Errorf("expected no enemies heading to a, got %v", first)
+    }
+}
+
+func TestFirstToClaim(t *testing.T) {
+    s := lineState(30, 3, 2)
+    s.Nodes["c"].Edges["b"].Units[0] = state.Units{"y": 3}
+    arrivals := CountAllUnits("x", s)["b"].Arrivals
+
+    // y lands on b in 2 turns, so x only gets there first from a 1 turn edge
+    if !FirstToClaim("x", s.Nodes["b"], arrivals, 1) {
+        t.Errorf("expected x to get to b first in 1 turn")
+    }
+    if FirstToClaim("x", s.Nodes["b"], arrivals, 2) || FirstToClaim("x", s.Nodes["b"], arrivals, 3) {
+        t.Errorf("expected y to get to b first, or at the same time")
+    }
+    // my own units on their way count too, and so does anyone already on the node
+    if FirstToClaim("y", s.Nodes["b"], arrivals, 3) {
+        t.Errorf("expected y not to race its own units")
+    }
+    if !FirstToClaim("x", s.Nodes["a"], CountAllUnits("x", s)["a"].Arrivals, 3) {
+        t.Errorf("expected nothing to race for a")
+    }
+    s.Nodes["a"].Units["z"] = 1
+    if FirstToClaim("x", s.Nodes["a"], CountAllUnits("x", s)["a"].Arrivals, 3) {
+        t.Errorf("expected a to be claimed already")
+    }
+}
@@ -0,0 +1,162 @@
+package common
+
+import (
+    "container/list"
+    "crypto/sha1"
+    "encoding/hex"
+    "fmt"
+    "github.com/zond/stockholm-ai/state"
+    "sync"
+)
+
+// number of maps the process wide topology cache remembers
+const TOPOLOGY_CACHE_SIZE = 64
+
+/*
+Fingerprint returns a canonical hash of the map in s: node ids and sizes, and every edge with its length.
+Units are ignored, so every state of the same game has the same fingerprint.
+*/
+func Fingerprint(s *state.State) string {
+    hash := sha1.New()
+    for _, nodeId := range SortedNodeIds(s) {
+        node := s.Nodes[nodeId]
+        fmt.Fprintf(hash, "%q:%d[", nodeId, node.Size)
+        for _, edge := range SortedEdges(node) {
+            fmt.Fprintf(hash, "%q:%d,", edge.Dst, len(edge.Units))
+        }
+        fmt.Fprint(hash, "]")
+    }
+    return hex.EncodeToString(hash.Sum(nil))
+}
+
+/*
+Topology holds everything about a map that never changes during a game.
+Get it with GetTopology, which only calculates it once per map. It also tells apart the games played on it, see GameOf.
+All fields and methods are safe to use from concurrent games.
+*/
+type Topology struct {
+    Fingerprint string
+    Distances   *DistanceMatrix
+
+    structureOnce sync.Once
+    structure     *Structure
+
+    values valueStore
+
+    gamesMutex sync.Mutex
+    games      []*Game // most recently seen first
+}
+
+// a value calculated once by whoever asks for it first
+type storedValue struct {
+    once  sync.Once
+    value interface{}
+}
+
+// values stored by key, each calculated once
+type valueStore struct {
+    mutex  sync.Mutex
+    values map[string]*storedValue
+}
+
+/*
+value returns the value stored under key, calling compute to create it the first time.
+compute runs without holding the lock, so other keys stay available meanwhile and compute may ask for
+them, but asking for the same key again from inside compute never returns.
+*/
+func (self *valueStore) value(key string, compute func() interface{}) interface{} {
+    self.mutex.Lock()
+    if self.values == nil {
+        self.values = make(map[string]*storedValue)
+    }
+    entry, found := self.values[key]
+    if !found {
+        entry = &storedValue{}
+        self.values[key] = entry
+    }
+    self.mutex.Unlock()
+
+    entry.once.Do(func() {
+        entry.value = compute()
+    })
+    return entry.value
+}
+
+// Structure returns the chokepoint analysis of the map, calculating it the first time. s must have this topology.
+func (self *Topology) Structure(s *state.State) *Structure {
+    self.structureOnce.Do(func() {
+        self.structure = NewStructure(s, self.Distances)
+    })
+    return self.structure
+}
+
+// Value returns the value stored under key (e.g. an opening plan), calling compute to create it the first time
+func (self *Topology) Value(key string, compute func() interface{}) interface{} {
+    return self.values.value(key, compute)
+}
+
+/*
+TopologyCache is an LRU cache of topologies keyed by fingerprint, safe for concurrent use
+*/
+type TopologyCache struct {
+    mutex    sync.Mutex
+    capacity int
+    order    *list.List // of *Topology, most recently used first
+    entries  map[string]*list.Element
+}
+
+// NewTopologyCache creates a cache remembering at most capacity maps
+func NewTopologyCache(capacity int) *TopologyCache {
+    return &TopologyCache{
+        capacity: capacity,
+        order:    list.New(),
+        entries:  make(map[string]*list.Element, capacity),
+    }
+}
+
+// Get returns the topology of s, calculating it if this map isn't in the cache
+func (self *TopologyCache) Get(s *state.State) *Topology {
+    fingerprint := Fingerprint(s)
+    self.mutex.Lock()
+    if element, found := self.entries[fingerprint]; found {
+        self.order.MoveToFront(element)
+        self.mutex.Unlock()
+        return element.Value.(*Topology)
+    }
+    self.mutex.Unlock()
+
+    // calculate without holding the lock, two games on a new map may both do it but only one is kept
+    topology := &Topology{
+        Fingerprint: fingerprint,
+        Distances:   NewDistanceMatrix(s),
+    }
+
+    self.mutex.Lock()
+    defer self.mutex.Unlock()
+    if element, found := self.entries[fingerprint]; found {
+        self.order.MoveToFront(element)
+        return element.Value.(*Topology)
+    }
+    self.entries[fingerprint] = self.order.PushFront(topology)
+    for self.order.Len() > self.capacity {
+        oldest := self.order.Back()
+        self.order.Remove(oldest)
+        delete(self.entries, oldest.Value.(*Topology).Fingerprint)
+    }
+    return topology
+}
+
+// Len returns the number of maps in the cache
+func (self *TopologyCache) Len() int {
+    self.mutex.Lock()
+    defer self.mutex.Unlock()
+    return self.order.Len()
+}
+
+// the process wide cache used by GetTopology
+var topologies = NewTopologyCache(TOPOLOGY_CACHE_SIZE)
+
+// GetTopology returns the topology of s from the process wide cache
+func GetTopology(s *state.State) *Topology {
+    return topologies.Get(s)
+}
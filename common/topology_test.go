@@ -0,0 +1,93 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+    "testing"
+    "time"
+)
+
+func TestFingerprint(t *testing.T) {
+    s := lineState(30, 3, 2)
+    fingerprint := Fingerprint(s)
+
+    // units don't change the map
+    s.Nodes["a"].Units["x"] = 5
+    edge := s.Nodes["a"].Edges["b"]
+    edge.Units[1] = map[state.PlayerId]int{"x": 2}
+    if Fingerprint(s) != fingerprint {
+        t.Errorf("expected units not to change the fingerprint")
+    }
+    if Fingerprint(lineState(30, 3, 3)) == fingerprint {
+        t.Errorf("expected a different edge length to change the fingerprint")
+    }
+    if Fingerprint(lineState(31, 3, 2)) == fingerprint {
+        t.Errorf("expected a different node size to change the fingerprint")
+    }
+}
+
+func TestTopologyCache(t *testing.T) {
+    cache := NewTopologyCache(2)
+    first := cache.Get(lineState(30, 1, 1))
+    if cache.Get(lineState(30, 1, 1)) != first {
+        t.Errorf("expected the same map to give the cached topology")
+    }
+    second := cache.Get(lineState(30, 2, 2))
+    // the first map was used more recently, so adding a third map evicts the second
+    cache.Get(lineState(30, 1, 1))
+    cache.Get(lineState(30, 3, 3))
+    if cache.Len() != 2 {
+        t.Errorf("expected 2 maps in the cache, got %v", cache.Len())
+    }
+    if cache.Get(lineState(30, 1, 1)) != first {
+        t.Errorf("expected the most recently used map to still be cached")
+    }
+    if cache.Get(lineState(30, 2, 2)) == second {
+        t.Errorf("expected the least recently used map to be evicted")
+    }
+
+    calls := 0
+    compute := func() interface{} {
+        calls++
+        return calls
+    }
+    first.Value("plan", compute)
+    if value := first.Value("plan", compute); value != 1 || calls != 1 {
+        t.Errorf("expected the value to be calculated once, got %v after %v calls", value, calls)
+    }
+}
+
+func TestTopologyValueUnlocked(t *testing.T) {
+    topology := NewTopologyCache(1).Get(lineState(30, 1, 1))
+
+    // a value may be built from other values
+    done := make(chan interface{})
+    go func() {
+        done <- topology.Value("plan", func() interface{} {
+            return topology.Value("distances", func() interface{} { return 2 }).(int) + 1
+        })
+    }()
+    select {
+    case value := <-done:
+        if value != 3 {
+            t.Errorf("expected 3, got %v", value)
+        }
+    case <-time.After(10 * time.Second):
+        t.Fatalf("expected Value to be callable from inside compute")
+    }
+
+    // a slow value doesn't hold up the others
+    started, release := make(chan bool), make(chan bool)
+    go topology.Value("slow", func() interface{} {
+        started <- true
+        <-release
+        return nil
+    })
+    <-started
+    if value := topology.Value("fast", func() interface{} { return 1 }); value != 1 {
+        t.Errorf("expected 1, got %v", value)
+    }
+    close(release)
+    if value := topology.Value("slow", func() interface{} { return 2 }); value != nil {
+        t.Errorf("expected the slow value to be calculated once, got %v", value)
+    }
+}
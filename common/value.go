@@ -0,0 +1,121 @@
+package common
+
+import (
+    "github.com/zond/stockholm-ai/state"
+)
+
+/*
+   OrderValue estimates what sending Units soldiers from Src to Dst is worth, counted in units at the horizon.
+
+   EV is the expected change in (my units - enemy units) compared to not sending them:
+   - if the soldiers capture (or hold) Dst, we gain DstGain: the difference in mine minus enemy units at Dst at the horizon,
+     which includes combat losses on both sides and the growth of the survivors
+   - with probability 1 - CaptureProbability enemy reinforcements arrive in time, and the soldiers just trade 1 for 1
+   - either way we pay SrcCost: the units (and their growth) that Src would have had at the horizon
+   Fights on the way kill as many enemies as they cost us, so TransitLoss only reduces the soldiers that arrive.
+*/
+type OrderValue struct {
+    Arrival            int // turns until the soldiers land on Dst
+    TransitLoss        int // soldiers killed on nodes along the way
+    Arriving           int // soldiers landing on Dst
+    CombatLoss         int // soldiers killed in the fight on Dst
+    CaptureProbability float64
+    DstGain            int
+    SrcCost            int
+    EV                 float64
+}
+
+/*
+Evaluator scores orders for one player in one state. Create one per Orders call with NewEvaluator.
+*/
+type Evaluator struct {
+    me        state.PlayerId
+    s         *state.State
+    horizon   int
+    Distances *DistanceMatrix
+    Counts    map[state.NodeId]*UnitCounts
+    Influence InfluenceMap
+}
+
+// NewEvaluator prepares the analyses needed to score orders of me in s, looking horizon turns ahead
+func NewEvaluator(me state.PlayerId, s *state.State, horizon int) *Evaluator {
+    distances := GetTopology(s).Distances
+    return &Evaluator{
+        me:        me,
+        s:         s,
+        horizon:   horizon,
+        Distances: distances,
+        Counts:    CountAllUnits(me, s),
+        Influence: NewInfluenceMap(s, distances, horizon),
+    }
+}
+
+// my units and enemy units in a forecast
+func (self *Evaluator) split(forecast NodeForecast) (mine, enemy int) {
+    if forecast.Owner == self.me {
+        return forecast.Units, 0
+    }
+    return 0, forecast.Units
+}
+
+// Score estimates the value of sending units soldiers from src towards dst (which doesn't have to be adjacent)
+func (self *Evaluator) Score(src, dst state.NodeId, units int) (result OrderValue) {
+    srcNode, dstNode := self.s.Nodes[src], self.s.Nodes[dst]
+    result.Arrival = self.Distances.Distance(src, dst)
+    if srcNode == nil || dstNode == nil || units <= 0 || src == dst || result.Arrival == UNREACHABLE {
+        return
+    }
+    horizon := Max(self.horizon, result.Arrival)
+
+    // 1. what Src loses
+    srcUnits := srcNode.Units[self.me]
+    units = Min(units, srcUnits)
+    result.SrcCost = GrowFor(srcUnits, srcNode.Size, horizon) - GrowFor(srcUnits-units, srcNode.Size, horizon)
+
+    // 2. fights on enemy held nodes along the way
+    result.Arriving = units
+    for _, hop := range self.Distances.Path(src, dst) {
+        if hop == dst {
+            break
+        }
+        turn := self.Distances.Distance(src, hop)
+        forecast := PredictCombat(self.s.Nodes[hop], self.s.Nodes[hop].Units, self.Counts[hop].Arrivals, turn)
+        if _, enemy := self.split(forecast[turn-1]); enemy > 0 {
+            loss := Min(enemy, result.Arriving)
+            result.TransitLoss += loss
+            result.Arriving -= loss
+        }
+    }
+
+    // 3. the fight on Dst, compared to what would happen there anyway
+    with := make(Timeline)
+    with.merge(self.Counts[dst].Arrivals)
+    with.addUnits(result.Arrival, self.me, result.Arriving)
+    forecastWith := PredictCombat(dstNode, dstNode.Units, with, horizon)
+    forecastWithout := PredictCombat(dstNode, dstNode.Units, self.Counts[dst].Arrivals, horizon)
+    myWith, enemyWith := self.split(forecastWith[horizon-1])
+    myWithout, enemyWithout := self.split(forecastWithout[horizon-1])
+    result.DstGain = (myWith - myWithout) + (enemyWithout - enemyWith)
+
+    myAtArrival, _ := self.split(forecastWith[result.Arrival-1])
+    myWithoutAtArrival, _ := self.split(forecastWithout[result.Arrival-1])
+    result.CombatLoss = Max(0, result.Arriving-(myAtArrival-myWithoutAtArrival))
+
+    // 4. how likely is it that we keep it: compare our units with enemies that could still get there in time
+    if myAtArrival > 0 {
+        counted := 0
+        for turn := 1; turn <= result.Arrival; turn++ {
+            counted += self.Counts[dst].Arrivals.Enemy(self.me, turn)
+        }
+        counted += CountNodeUnits(self.me, dstNode).EnemyUnits
+        if extra := self.Influence[dst].Enemy(self.me, result.Arrival) - counted; extra > 0 {
+            result.CaptureProbability = float64(myAtArrival) / float64(myAtArrival+extra)
+        } else {
+            result.CaptureProbability = 1
+        }
+        result.EV = result.CaptureProbability*float64(result.DstGain) + (1-result.CaptureProbability)*float64(result.Arriving) - float64(result.SrcCost)
+    } else {
+        result.EV = float64(result.DstGain - result.SrcCost)
+    }
+    return
+}
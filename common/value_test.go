@@ -0,0 +1,35 @@
+package common
+
+import (
+    "testing"
+)
+
+func TestEvaluatorScore(t *testing.T) {
+    s := lineState(30, 1, 2)
+    s.Nodes["b"].Units["x"] = 30
+    s.Nodes["c"].Units["y"] = 10
+    evaluator := NewEvaluator("x", s, 10)
+
+    // claiming an empty node is worth its growth
+    claim := evaluator.Score("b", "a", 5)
+    if claim.Arrival != 1 || claim.Arriving != 5 || claim.CaptureProbability != 1 || claim.EV <= 0 {
+        t.Errorf("expected claiming a to pay off, got %+v", claim)
+    }
+
+    // attacking with too few soldiers just trades them 1 for 1 and gives up growth at home
+    weak := evaluator.Score("b", "c", 5)
+    if weak.CaptureProbability != 0 || weak.CombatLoss != 5 || weak.EV > claim.EV {
+        t.Errorf("expected a weak attack on c to fail, got %+v", weak)
+    }
+
+    // a big enough attack takes the node
+    strong := evaluator.Score("b", "c", 20)
+    if strong.Arrival != 2 || strong.CaptureProbability != 1 || strong.CombatLoss == 0 || strong.EV <= weak.EV {
+        t.Errorf("expected a strong attack on c to succeed, got %+v", strong)
+    }
+
+    // nonsense orders are worth nothing
+    if none := evaluator.Score("b", "b", 5); none.EV != 0 {
+        t.Errorf("expected staying home to be worth nothing, got %+v", none)
+    }
+}
@@ -10,7 +10,7 @@ import (
 /*
 Defensive AI
 - Sends 1 unit to adjacent unclaimed nodes only
-- Maintains node.size/2 units at each node
+- Maintains enough units at each node to grow at full speed (common.FastestGrowth, a bit under node.size/2)
 - Attacks with any extras
 
 v1 Algorithm:
@@ -18,10 +18,40 @@ v1 Algorithm:
     a. For each edge:
         i. ensure that we still have guys available
         ii. if edge.Dst is unclaimed and has no units (friendly or enemy) on their way will beat me there, send 1 guy
-    b. If units > node.size/2, send (units - node.size/2) units towards nearest enemy or unclaimed node
+    b. If units > garrison, send (units - garrison) units towards the enemy node that costs the fewest units to
+       capture (common.UnitsToCapture), or if enough units are available to capture it, that many instead
 */
 type DefensiveAi1 struct{}
 
+/*
+cheapestAttack finds the enemy node next to my territory that costs the fewest men to capture from src, as predicted
+by common.UnitsToCapture for soldiers landing there after the shortest distance, and returns the edge to send them
+along and the cost
+*/
+func cheapestAttack(me state.PlayerId, s *state.State, src state.NodeId, nodeIds []state.NodeId, unitCounts map[state.NodeId]*common.UnitCounts, distances *common.DistanceMatrix) (cheapestEdge state.NodeId, cheapest int, found bool) {
+    for _, dst := range nodeIds {
+        dstUnits := unitCounts[dst]
+        if dst == src {
+            continue
+        }
+        if dstUnits.Adjacent && dstUnits.EnemyUnits > 0 {
+            dist := distances.Distance(src, dst)
+            if dist == common.UNREACHABLE {
+                continue
+            }
+            // how many men do I need to land there to capture this node
+            node := s.Nodes[dst]
+            thisCost := common.UnitsToCapture(me, node, node.Units, dstUnits.Arrivals, dist)
+            if !found || thisCost < cheapest {
+                cheapest = thisCost
+                cheapestEdge = distances.NextHop(src, dst)
+                found = true
+            }
+        }
+    }
+    return
+}
+
 /*
 Orders will analyze all nodes in s and return orders for each one
 */
@@ -31,18 +61,23 @@ func (self DefensiveAi1) Orders(logger stockholmCommon.Logger, me state.PlayerId
 
     // gather data
     unitCounts := common.CountAllUnits(me, s)
+    distances := common.GetTopology(s).Distances
+
+    // always visit nodes in the same order so the same state gives the same orders
+    nodeIds := common.SortedNodeIds(s)
 
     // 1. For each node that has >1 unit
-    for nodeId, node := range s.Nodes {
+    for _, nodeId := range nodeIds {
+        node := s.Nodes[nodeId]
         if units := node.Units[me]; units > 1 {
             // a. For each edge:
-            for _, edge := range node.Edges {
+            for _, edge := range common.SortedEdges(node) {
                 // i. ensure that we still have guys available
                 if units <= 1 {
                     break
                 }
                 // ii. if edge.Dst is unclaimed and has no units (friendly or enemy) on their way that will beat me there, send 1 guy
-                if unitCounts[edge.Dst].Delay > len(edge.Units) {
+                if common.FirstToClaim(me, s.Nodes[edge.Dst], unitCounts[edge.Dst].Arrivals, len(edge.Units)) {
                     result = append(result, state.Order{
                         Src:   edge.Src,
                         Dst:   edge.Dst,
@@ -51,30 +86,14 @@ func (self DefensiveAi1) Orders(logger stockholmCommon.Logger, me state.PlayerId
                     units--
                 }
             }
-            // b. If units > node.size/2, send up to (units - node.size/2) available units towards nearest/least defended enemy node
-            // if we
+            // b. If units > garrison, send up to (units - garrison) available units towards the cheapest enemy node to capture
+            garrison := common.FastestGrowth(node.Size)
             available := unitCounts[nodeId].Units - unitCounts[nodeId].EnemyUnits
-            if sendUnits := common.Min(available, units-node.Size/2); sendUnits > 0 {
-                var cheapest float64 = -1
-                cheapestEdge := nodeId
-                for dst, dstUnits := range unitCounts {
-                    if dst == nodeId {
-                        continue
-                    }
-                    if dstUnits.Adjacent && dstUnits.EnemyUnits > 0 {
-                        path := s.Path(node.Id, dst, nil)
-                        // how many men do I lose to capture this node
-                        thisCost := float64(dstUnits.EnemyUnits-dstUnits.Units) * (1 + float64(len(path))*0.2)
-                        if cheapest == -1 || thisCost < cheapest {
-                            cheapest = thisCost
-                            cheapestEdge = path[0]
-                        }
-                    }
-                }
-                if cheapest != -1 {
+            if sendUnits := common.Min(available, units-garrison); sendUnits > 0 {
+                if cheapestEdge, cheapest, found := cheapestAttack(me, s, nodeId, nodeIds, unitCounts, distances); found {
                     //if we have enough units to capture the node, send that many
-                    if int(cheapest) < common.Min(units-1, available) && int(cheapest) > sendUnits {
-                        sendUnits = int(cheapest)
+                    if cheapest < common.Min(units-1, available) && cheapest > sendUnits {
+                        sendUnits = cheapest
                     }
                     result = append(result, state.Order{
                         Src:   node.Id,
@@ -85,6 +104,12 @@ func (self DefensiveAi1) Orders(logger stockholmCommon.Logger, me state.PlayerId
             }
         }
     }
+    // drop or fix any invalid orders
+    result, changes := common.NormalizeOrders(me, s, result)
+    for _, change := range changes {
+        logger.Printf("NormalizeOrders %v", change)
+    }
+
     // all done, return the orders list
     return
 }
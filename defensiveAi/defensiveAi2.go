@@ -0,0 +1,164 @@
+package defensiveAi
+
+import (
+    common "github.com/miridius/ai/common"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    state "github.com/zond/stockholm-ai/state"
+)
+
+// how many turns ahead to look for enemies that could attack a node
+const THREAT_TURNS = 5
+
+/*
+Defensive AI v2
+- Sends 1 unit to adjacent unclaimed nodes only, like v1
+- Sets the garrison of each node from the enemies on their way to it, on top of enough units to grow at full speed
+- Reinforces nodes that will be hit with the surplus of other nodes
+- Attacks with the surplus like v1, but only nodes out of reach of a stronger enemy go all in on an attack,
+  and the surplus of safe interior nodes moves to the frontier instead of sitting idle
+
+v2 Algorithm:
+1. For each node that has >1 unit, claim adjacent unclaimed nodes exactly like v1
+2. For each node I hold:
+    a. hold = units needed to hold it against the enemies on their way (common.UnitsToHold)
+    b. garrison = max(hold, common.FastestGrowth(node.Size))
+    c. ask for the units still missing to hold it, on top of its own units and mine landing there on each turn of
+       the same arrival timeline, as reinforcements (common.ReinforcementsToHold)
+    d. if units > garrison, the rest is surplus
+    e. the node is exposed if the enemies that could reach it within THREAT_TURNS outnumber the units I could get there
+3. Assign surplus to reinforcements with common.AllocateSoldiers
+4. Send any remaining surplus into the cheapest attack like v1, priced on the same arrival timeline with
+   common.UnitsToCapture (exposed nodes never go below their garrison to do it), or if there is nothing to attack,
+   from interior nodes towards the nearest frontier node
+*/
+type DefensiveAi2 struct{}
+
+/*
+threat returns how many units node needs to hold out against the enemies on their way, how many of those are
+missing given the units on it and mine on their way, and whether it is exposed: the enemies that could reach it
+within THREAT_TURNS outnumber the units I could get there
+*/
+func threat(me state.PlayerId, node *state.Node, units int, counts *common.UnitCounts, influence *common.Influence) (hold, missing int, exposed bool) {
+    // enough to beat all of them at once always holds
+    enough := 1
+    for _, turn := range counts.Arrivals.Turns() {
+        enough += counts.Arrivals.Enemy(me, turn)
+    }
+    hold = common.UnitsToHold(me, node, counts.Arrivals, enough)
+    missing = common.ReinforcementsToHold(me, node, units, counts.Arrivals, enough)
+    return hold, missing, influence.Net(me, THREAT_TURNS) < 0
+}
+
+/*
+Orders will analyze all nodes in s and return orders for each one
+*/
+func (self DefensiveAi2) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) (result state.Orders) {
+
+    logger.Printf("DefensiveAi2 calculating orders for player: %v", me)
+
+    // gather data
+    unitCounts := common.CountAllUnits(me, s)
+    distances := common.GetTopology(s).Distances
+    influence := common.NewInfluenceMap(s, distances, THREAT_TURNS)
+    logistics := common.NewLogistics(me, s, distances)
+
+    // always visit nodes in the same order so the same state gives the same orders
+    nodeIds := common.SortedNodeIds(s)
+
+    // units left on each node after claiming
+    units := make(map[state.NodeId]int, len(s.Nodes))
+
+    // 1. For each node that has >1 unit, claim adjacent unclaimed nodes
+    for _, nodeId := range nodeIds {
+        node := s.Nodes[nodeId]
+        units[nodeId] = node.Units[me]
+        for _, edge := range common.SortedEdges(node) {
+            if units[nodeId] <= 1 {
+                break
+            }
+            if common.FirstToClaim(me, s.Nodes[edge.Dst], unitCounts[edge.Dst].Arrivals, len(edge.Units)) {
+                result = append(result, state.Order{
+                    Src:   edge.Src,
+                    Dst:   edge.Dst,
+                    Units: 1,
+                })
+                units[nodeId]--
+            }
+        }
+    }
+
+    // 2. garrisons, reinforcements and surplus
+    surplus := make(map[state.NodeId]int, len(s.Nodes))
+    // units a node can spare for a decisive attack
+    spare := make(map[state.NodeId]int, len(s.Nodes))
+    supplies := []common.Supply{}
+    demands := []common.Demand{}
+    for _, nodeId := range nodeIds {
+        node := s.Nodes[nodeId]
+        if !logistics.IsOwned(nodeId) {
+            continue
+        }
+        hold, missing, exposed := threat(me, node, units[nodeId], unitCounts[nodeId], influence[nodeId])
+        garrison := common.Max(hold, common.FastestGrowth(node.Size))
+        if missing > 0 {
+            logger.Printf("%v needs %v more units", nodeId, missing)
+            demands = append(demands, common.Demand{Node: nodeId, Units: missing})
+        }
+        // exposed nodes never go below their garrison, other nodes may go all in on an attack
+        if exposed {
+            spare[nodeId] = units[nodeId] - garrison
+        } else {
+            spare[nodeId] = units[nodeId] - common.Max(hold, 1)
+        }
+        if units[nodeId] > garrison {
+            surplus[nodeId] = units[nodeId] - garrison
+            supplies = append(supplies, common.Supply{Node: nodeId, Units: surplus[nodeId]})
+        }
+    }
+
+    // 3. reinforce the nodes that will be hit
+    for _, assignment := range common.AllocateSoldiers(supplies, demands, distances) {
+        src := assignment.Supply.Node
+        surplus[src] -= assignment.Units
+        spare[src] -= assignment.Units
+        result = append(result, state.Order{
+            Src:   src,
+            Dst:   distances.NextHop(src, assignment.Target),
+            Units: assignment.Units,
+        })
+    }
+
+    // 4. move the rest to the frontier, or attack with it
+    for _, nodeId := range nodeIds {
+        sendUnits := surplus[nodeId]
+        if sendUnits <= 0 {
+            continue
+        }
+        if cheapestEdge, cheapest, found := cheapestAttack(me, s, nodeId, nodeIds, unitCounts, distances); found {
+            //if we have enough units to capture the node, send that many
+            if cheapest <= spare[nodeId] && cheapest > sendUnits {
+                sendUnits = cheapest
+            }
+            result = append(result, state.Order{
+                Src:   nodeId,
+                Dst:   cheapestEdge,
+                Units: sendUnits,
+            })
+        } else if next, found := logistics.NextHop[nodeId]; found {
+            result = append(result, state.Order{
+                Src:   nodeId,
+                Dst:   next,
+                Units: sendUnits,
+            })
+        }
+    }
+
+    // drop or fix any invalid orders
+    result, changes := common.NormalizeOrders(me, s, result)
+    for _, change := range changes {
+        logger.Printf("NormalizeOrders %v", change)
+    }
+
+    // all done, return the orders list
+    return
+}
@@ -1,7 +1,10 @@
 package defensiveAi
 
 import (
+    "github.com/miridius/ai/aitest"
+    common "github.com/miridius/ai/common"
     "github.com/zond/stockholm-ai/state"
+    "io/ioutil"
     "log"
     "os"
     "testing"
@@ -37,3 +40,103 @@ func TestDefensiveOrders(t *testing.T) {
     //print winner
     logger.Printf("onlyPlayerLeft: %v", *onlyPlayerLeft)
 }
+
+func TestDefensiveOrdersDeterministic(t *testing.T) {
+    // the same state must always give the same orders
+    aitest.CheckDeterministic(t, 50, DefensiveAi1{})
+}
+
+func TestCheapestAttack(t *testing.T) {
+    // near (1 turn away) has 10 enemy units, far (3 turns away) has 8 but is too small to grow past 9,
+    // and enemy is 2 turns from far
+    newState := func() *state.State {
+        s := aitest.NewState(60, "home", "near", "far", "enemy")
+        s.Nodes["far"].Size = 9
+        aitest.Connect(s, "home", "near", 1)
+        aitest.Connect(s, "home", "far", 3)
+        aitest.Connect(s, "far", "enemy", 2)
+        s.Nodes["home"].Units["me"] = 30
+        s.Nodes["near"].Units["them"] = 10
+        s.Nodes["far"].Units["them"] = 8
+        return s
+    }
+    attack := func(s *state.State) (state.NodeId, int) {
+        edge, cost, found := cheapestAttack("me", s, "home", common.SortedNodeIds(s), common.CountAllUnits("me", s), common.GetTopology(s).Distances)
+        if !found {
+            t.Fatalf("expected an attack")
+        }
+        return edge, cost
+    }
+
+    // far has only grown to 9 units by the time my units land, near still has 10
+    if edge, cost := attack(newState()); edge != "far" || cost != 10 {
+        t.Errorf("expected to attack far with 10 units, got %v with %v", edge, cost)
+    }
+
+    // enemy reinforcements landing on far before my units make it more expensive
+    s := newState()
+    aitest.Send(s, "enemy", "far", "them", 5)
+    if edge, cost := attack(s); edge != "near" || cost != 11 {
+        t.Errorf("expected to attack near with 11 units, got %v with %v", edge, cost)
+    }
+}
+
+func TestDefensive2Garrisons(t *testing.T) {
+    logger := log.New(ioutil.Discard, "", 0)
+    // interior is safe inside my territory, home is about to be hit by 30 units from enemy
+    s := aitest.ThreatenedState(40, 10, 30)
+    sent := make(map[state.NodeId]map[state.NodeId]int)
+    for _, order := range (DefensiveAi2{}).Orders(logger, "me", s) {
+        if sent[order.Src] == nil {
+            sent[order.Src] = make(map[state.NodeId]int)
+        }
+        sent[order.Src][order.Dst] += order.Units
+    }
+    t.Logf("orders: %v", sent)
+
+    // the unclaimed node is still claimed
+    if sent["home"]["unclaimed"] != 1 {
+        t.Errorf("expected home to claim unclaimed with 1 unit, got %v", sent["home"])
+    }
+    // the frontier doesn't attack while it is about to be hit
+    if sent["home"]["enemy"] > 0 {
+        t.Errorf("expected home to keep its units for defence, got %v", sent["home"])
+    }
+    // the safe interior node sends at least its surplus to the threatened frontier, more if that's what it takes to win it back
+    if surplus := 40 - common.FastestGrowth(60); sent["interior"]["home"] < surplus || sent["interior"]["home"] > 39 {
+        t.Errorf("expected interior to send between its surplus of %v and 39 units to home, got %v", surplus, sent["interior"])
+    }
+
+    // without the threat home attacks enemy instead, going below its garrison to send exactly enough to capture it
+    s = aitest.ThreatenedState(40, 20, 0)
+    unitCounts := common.CountAllUnits("me", s)
+    needed := common.UnitsToCapture("me", s.Nodes["enemy"], s.Nodes["enemy"].Units, unitCounts["enemy"].Arrivals, 3)
+    if surplus := 20 - common.FastestGrowth(60); needed <= surplus {
+        t.Fatalf("expected capturing enemy to take more than the surplus of %v, but it takes %v", surplus, needed)
+    }
+    for _, order := range (DefensiveAi2{}).Orders(logger, "me", s) {
+        if order.Src == "home" && order.Dst == "enemy" {
+            if order.Units != needed {
+                t.Errorf("expected home to attack enemy with %v units, got %v", needed, order.Units)
+            }
+            return
+        }
+    }
+    t.Errorf("expected home to attack enemy once it is no longer threatened")
+}
+
+func TestDefensive2OrdersDeterministic(t *testing.T) {
+    // the same state must always give the same orders
+    aitest.CheckDeterministic(t, 50, DefensiveAi2{})
+}
+
+func TestDefensive2Orders(t *testing.T) {
+    // v1 against v2 on a fixed set of maps, v2 must win more games
+    wins := aitest.Series(t, 20, 1000, map[state.PlayerId]aitest.AI{
+        "v1": DefensiveAi1{},
+        "v2": DefensiveAi2{},
+    })
+    if wins["v2"] <= wins["v1"] {
+        t.Errorf("expected v2 to win more games than v1, got %v", wins)
+    }
+}
@@ -0,0 +1,250 @@
+// features by Miridius
+package features
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    common "github.com/miridius/ai/common"
+    state "github.com/zond/stockholm-ai/state"
+    "io"
+    "strconv"
+)
+
+/*
+Feature extraction
+Turns a state into fixed length numeric vectors as seen by one player, to train evaluation functions offline.
+
+The layout is versioned: never reorder, rename or remove features without bumping VERSION,
+since trained weights depend on each feature staying at the same index. New features go at the end.
+
+v1 layout: see NodeFeatureNames and GameFeatureNames
+*/
+const VERSION = 1
+
+// how far ahead threat and support are counted
+var threatTurns = []int{1, 3, 5}
+
+// names of the per node features, in order
+var NodeFeatureNames = []string{
+    "my_units",             // my units on the node
+    "enemy_units",          // enemy units on the node
+    "size",                 // node.Size
+    "fill",                 // units on the node / node.Size
+    "owned",                // 1 if I have units on the node
+    "enemy_owned",          // 1 if an enemy has units on the node
+    "unclaimed",            // 1 if nobody has units on the node
+    "incoming_friendly",    // my units on edges heading to the node
+    "incoming_enemy",       // enemy units on edges heading to the node
+    "threat_1",             // enemy units that could be on the node within 1 turn
+    "threat_3",             // ... 3 turns
+    "threat_5",             // ... 5 turns
+    "support_1",            // my units that could be on the node within 1 turn
+    "support_3",            // ... 3 turns
+    "support_5",            // ... 5 turns
+    "frontier",             // 1 if the node is one of my frontier nodes
+    "distance_to_frontier", // turns to my nearest frontier node, -1 if I have none
+    "growth",               // units the owner will gain from growth next turn
+}
+
+// names of the whole game features, in order
+var GameFeatureNames = []string{
+    "players",         // players still alive
+    "nodes",           // nodes on the map
+    "unclaimed_nodes", // nodes nobody has units on
+    "my_rank",         // my position in the standings, 0 is the leader, -1 if I'm dead
+    "my_nodes",
+    "my_size",
+    "my_node_units",
+    "my_transit_units",
+    "my_growth",
+    "enemy_nodes", // summed over all enemies
+    "enemy_size",
+    "enemy_node_units",
+    "enemy_transit_units",
+    "enemy_growth",
+    "leader_units", // units of the strongest player other than me
+    "frontier_nodes",
+    "interior_nodes",
+    "regions",
+}
+
+// NodeFeatures are the features of a single node
+type NodeFeatures struct {
+    Node   state.NodeId
+    Values []float64
+}
+
+// Features are all features of a state as seen by Player
+type Features struct {
+    Version int
+    Player  state.PlayerId
+    Game    []float64
+    Nodes   []NodeFeatures // sorted by node id
+}
+
+func boolFeature(b bool) float64 {
+    if b {
+        return 1
+    }
+    return 0
+}
+
+/*
+Extract calculates all features of s as seen by me
+*/
+func Extract(me state.PlayerId, s *state.State) (result *Features) {
+    result = &Features{
+        Version: VERSION,
+        Player:  me,
+    }
+    distances := common.GetTopology(s).Distances
+    unitCounts := common.CountAllUnits(me, s)
+    influence := common.NewInfluenceMap(s, distances, threatTurns[len(threatTurns)-1])
+    logistics := common.NewLogistics(me, s, distances)
+    standings := common.Standings(s)
+
+    frontier := make([]state.NodeId, 0, len(logistics.Frontier))
+    nodeIds := common.SortedNodeIds(s)
+    for _, nodeId := range nodeIds {
+        if logistics.Frontier[nodeId] {
+            frontier = append(frontier, nodeId)
+        }
+    }
+
+    unclaimedNodes := 0
+    for _, nodeId := range nodeIds {
+        node := s.Nodes[nodeId]
+        counts := common.CountNodeUnits(me, node)
+        incoming := unitCounts[nodeId]
+        units := counts.Units + counts.EnemyUnits
+        if units == 0 {
+            unclaimedNodes++
+        }
+        distanceToFrontier := -1
+        for _, f := range frontier {
+            if dist := distances.Distance(nodeId, f); dist != common.UNREACHABLE && (distanceToFrontier < 0 || dist < distanceToFrontier) {
+                distanceToFrontier = dist
+            }
+        }
+        growth := 0
+        for _, numUnits := range node.Units {
+            growth += common.Grow(numUnits, node.Size) - numUnits
+        }
+        values := []float64{
+            float64(counts.Units),
+            float64(counts.EnemyUnits),
+            float64(node.Size),
+            float64(units) / float64(node.Size),
+            boolFeature(counts.Units > 0),
+            boolFeature(counts.EnemyUnits > 0),
+            boolFeature(units == 0),
+            float64(incoming.Units - counts.Units),
+            float64(incoming.EnemyUnits - counts.EnemyUnits),
+        }
+        for _, turns := range threatTurns {
+            values = append(values, float64(influence[nodeId].Enemy(me, turns)))
+        }
+        for _, turns := range threatTurns {
+            values = append(values, float64(influence[nodeId].Friendly(me, turns)))
+        }
+        values = append(values,
+            boolFeature(logistics.Frontier[nodeId]),
+            float64(distanceToFrontier),
+            float64(growth),
+        )
+        result.Nodes = append(result.Nodes, NodeFeatures{nodeId, values})
+    }
+
+    // Get gives an empty standing if I'm dead
+    mine, _ := standings.Get(me)
+    rank := -1
+    var enemy common.PlayerStanding
+    leaderUnits := 0
+    for index, standing := range standings {
+        if standing.Player == me {
+            rank = index
+            continue
+        }
+        enemy.Nodes += standing.Nodes
+        enemy.Size += standing.Size
+        enemy.NodeUnits += standing.NodeUnits
+        enemy.TransitUnits += standing.TransitUnits
+        enemy.Growth += standing.Growth
+        if standing.Units() > leaderUnits {
+            leaderUnits = standing.Units()
+        }
+    }
+    result.Game = []float64{
+        float64(len(standings)),
+        float64(len(s.Nodes)),
+        float64(unclaimedNodes),
+        float64(rank),
+        float64(mine.Nodes),
+        float64(mine.Size),
+        float64(mine.NodeUnits),
+        float64(mine.TransitUnits),
+        float64(mine.Growth),
+        float64(enemy.Nodes),
+        float64(enemy.Size),
+        float64(enemy.NodeUnits),
+        float64(enemy.TransitUnits),
+        float64(enemy.Growth),
+        float64(leaderUnits),
+        float64(len(logistics.Frontier)),
+        float64(len(logistics.Interior)),
+        float64(len(logistics.Regions)),
+    }
+    return
+}
+
+// JSON encodes the features, including the feature names so files are self describing
+func (self *Features) JSON() ([]byte, error) {
+    return json.Marshal(struct {
+        *Features
+        NodeFeatureNames []string
+        GameFeatureNames []string
+    }{self, NodeFeatureNames, GameFeatureNames})
+}
+
+func formatValues(values []float64) (result []string) {
+    result = make([]string, len(values))
+    for i, value := range values {
+        result[i] = strconv.FormatFloat(value, 'g', -1, 64)
+    }
+    return
+}
+
+// WriteNodeCSV writes one row per node: version, player, node, then the node features.
+// The header row is only written if header is true, so that many states can go in the same file.
+func (self *Features) WriteNodeCSV(w io.Writer, header bool) error {
+    writer := csv.NewWriter(w)
+    if header {
+        if err := writer.Write(append([]string{"version", "player", "node"}, NodeFeatureNames...)); err != nil {
+            return err
+        }
+    }
+    for _, node := range self.Nodes {
+        row := append([]string{strconv.Itoa(self.Version), string(self.Player), string(node.Node)}, formatValues(node.Values)...)
+        if err := writer.Write(row); err != nil {
+            return err
+        }
+    }
+    writer.Flush()
+    return writer.Error()
+}
+
+// WriteGameCSV writes a single row with version, player, then the game features.
+// The header row is only written if header is true.
+func (self *Features) WriteGameCSV(w io.Writer, header bool) error {
+    writer := csv.NewWriter(w)
+    if header {
+        if err := writer.Write(append([]string{"version", "player"}, GameFeatureNames...)); err != nil {
+            return err
+        }
+    }
+    if err := writer.Write(append([]string{strconv.Itoa(self.Version), string(self.Player)}, formatValues(self.Game)...)); err != nil {
+        return err
+    }
+    writer.Flush()
+    return writer.Error()
+}
@@ -0,0 +1,131 @@
+package features
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "github.com/miridius/ai/aitest"
+    "github.com/zond/stockholm-ai/state"
+    "testing"
+)
+
+func TestExtract(t *testing.T) {
+    s := aitest.SeededState(1, []state.PlayerId{"a", "b", "c", "d"})
+
+    features := Extract("a", s)
+    if len(features.Game) != len(GameFeatureNames) {
+        t.Errorf("expected %v game features, got %v", len(GameFeatureNames), len(features.Game))
+    }
+    if len(features.Nodes) != len(s.Nodes) {
+        t.Errorf("expected features for %v nodes, got %v", len(s.Nodes), len(features.Nodes))
+    }
+    for _, node := range features.Nodes {
+        if len(node.Values) != len(NodeFeatureNames) {
+            t.Errorf("expected %v features for node %v, got %v", len(NodeFeatureNames), node.Node, len(node.Values))
+        }
+    }
+
+    // JSON
+    data, err := features.JSON()
+    if err != nil {
+        t.Fatalf("encoding JSON: %v", err)
+    }
+    decoded := &Features{}
+    if err := json.Unmarshal(data, decoded); err != nil {
+        t.Fatalf("decoding JSON: %v", err)
+    }
+    if decoded.Version != VERSION || len(decoded.Nodes) != len(features.Nodes) {
+        t.Errorf("JSON round trip lost data: %+v", decoded)
+    }
+
+    // CSV
+    buffer := &bytes.Buffer{}
+    if err := features.WriteNodeCSV(buffer, true); err != nil {
+        t.Fatalf("writing CSV: %v", err)
+    }
+    rows, err := csv.NewReader(buffer).ReadAll()
+    if err != nil {
+        t.Fatalf("reading CSV: %v", err)
+    }
+    if len(rows) != len(s.Nodes)+1 || len(rows[0]) != len(NodeFeatureNames)+3 {
+        t.Errorf("unexpected CSV shape: %v rows of %v columns", len(rows), len(rows[0]))
+    }
+    buffer.Reset()
+    if err := features.WriteGameCSV(buffer, false); err != nil {
+        t.Fatalf("writing CSV: %v", err)
+    }
+    if rows, _ = csv.NewReader(buffer).ReadAll(); len(rows) != 1 || len(rows[0]) != len(GameFeatureNames)+2 {
+        t.Errorf("unexpected game CSV: %v", rows)
+    }
+}
+
+// compares values to the expected ones by feature name
+func checkValues(t *testing.T, what string, names []string, values []float64, expected map[string]float64) {
+    checked := 0
+    for index, name := range names {
+        if value, found := expected[name]; found {
+            checked++
+            if values[index] != value {
+                t.Errorf("%v: expected %v = %v, got %v", what, name, value, values[index])
+            }
+        }
+    }
+    if checked != len(expected) {
+        t.Errorf("%v: expected only known features, got %v", what, expected)
+    }
+}
+
+func TestExtractValues(t *testing.T) {
+    // d - a - b = c: x holds a and d and has 3 units on their way to b, y holds c and has 4 units on their way to b
+    s := aitest.NewState(20, "a", "b", "c", "d")
+    s.Nodes["b"].Size = 30
+    s.Nodes["c"].Size = 40
+    s.Nodes["d"].Size = 10
+    aitest.Connect(s, "d", "a", 1)
+    aitest.Connect(s, "a", "b", 1)
+    aitest.Connect(s, "b", "c", 2)
+    s.Nodes["a"].Units["x"] = 10
+    s.Nodes["d"].Units["x"] = 5
+    s.Nodes["c"].Units["y"] = 6
+    aitest.Send(s, "a", "b", "x", 3)
+    aitest.Send(s, "c", "b", "y", 4)
+
+    features := Extract("x", s)
+    nodes := make(map[state.NodeId][]float64)
+    for _, node := range features.Nodes {
+        nodes[node.Node] = node.Values
+    }
+    checkValues(t, "a", NodeFeatureNames, nodes["a"], map[string]float64{
+        "my_units": 10, "enemy_units": 0, "size": 20, "fill": 0.5, "owned": 1, "enemy_owned": 0, "unclaimed": 0,
+        "incoming_friendly": 0, "incoming_enemy": 0,
+        "threat_1": 0, "threat_3": 10, "threat_5": 10,
+        "support_1": 15, "support_3": 18, "support_5": 18,
+        "frontier": 1, "distance_to_frontier": 0, "growth": 1,
+    })
+    checkValues(t, "b", NodeFeatureNames, nodes["b"], map[string]float64{
+        "my_units": 0, "fill": 0, "owned": 0, "unclaimed": 1,
+        "incoming_friendly": 3, "incoming_enemy": 4,
+        "threat_1": 0, "threat_3": 10,
+        "support_1": 13, "support_3": 18,
+        "frontier": 0, "distance_to_frontier": 1, "growth": 0,
+    })
+    checkValues(t, "c", NodeFeatureNames, nodes["c"], map[string]float64{
+        "my_units": 0, "enemy_units": 6, "enemy_owned": 1, "fill": 0.15, "distance_to_frontier": 3, "growth": 2,
+    })
+    checkValues(t, "d", NodeFeatureNames, nodes["d"], map[string]float64{
+        "my_units": 5, "fill": 0.5, "owned": 1,
+        "threat_3": 0, "threat_5": 10,
+        "frontier": 0, "distance_to_frontier": 1, "growth": 1,
+    })
+    checkValues(t, "game", GameFeatureNames, features.Game, map[string]float64{
+        "players": 2, "nodes": 4, "unclaimed_nodes": 1, "my_rank": 0,
+        "my_nodes": 2, "my_size": 30, "my_node_units": 15, "my_transit_units": 3, "my_growth": 2,
+        "enemy_nodes": 1, "enemy_size": 40, "enemy_node_units": 6, "enemy_transit_units": 4, "enemy_growth": 2,
+        "leader_units": 10, "frontier_nodes": 1, "interior_nodes": 1, "regions": 1,
+    })
+
+    // a player without units ranks -1
+    if rank := Extract("z", s).Game[3]; rank != -1 {
+        t.Errorf("expected a dead player to rank -1, got %v", rank)
+    }
+}
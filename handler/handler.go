@@ -5,6 +5,8 @@ import (
     "github.com/miridius/ai/aggressiveAi"
     "github.com/miridius/ai/balancedAi"
     "github.com/miridius/ai/defensiveAi"
+    "github.com/miridius/ai/mctsAi"
+    "github.com/miridius/ai/metaAi"
     "github.com/zond/stockholm-ai/ai"
     "github.com/zond/stockholm-ai/hub/common"
     "net/http"
@@ -12,13 +14,19 @@ import (
 
 func init() {
     http.HandleFunc("/balanced/v1", ai.HTTPHandlerFunc(common.GAELoggerFactory, balancedAi.BalancedAi1{}))
+    http.HandleFunc("/balanced/v2", ai.HTTPHandlerFunc(common.GAELoggerFactory, balancedAi.BalancedAi2{}))
     http.HandleFunc("/aggressive/v1.1", ai.HTTPHandlerFunc(common.GAELoggerFactory, aggressiveAi.AggressiveAi1{}))
+    http.HandleFunc("/aggressive/v2", ai.HTTPHandlerFunc(common.GAELoggerFactory, aggressiveAi.AggressiveAi2{}))
     http.HandleFunc("/aggressive/v1", ai.HTTPHandlerFunc(common.GAELoggerFactory, aggressiveAi.AggressiveAi1{}))
+    http.HandleFunc("/aggressive/optimal/v1", ai.HTTPHandlerFunc(common.GAELoggerFactory, aggressiveAi.OptimalAggressiveAi{}))
     http.HandleFunc("/defensive/v1", ai.HTTPHandlerFunc(common.GAELoggerFactory, defensiveAi.DefensiveAi1{}))
+    http.HandleFunc("/defensive/v2", ai.HTTPHandlerFunc(common.GAELoggerFactory, defensiveAi.DefensiveAi2{}))
+    http.HandleFunc("/mcts/v1", ai.HTTPHandlerFunc(common.GAELoggerFactory, mctsAi.MctsAi{}))
+    http.HandleFunc("/meta/v1", ai.HTTPHandlerFunc(common.GAELoggerFactory, metaAi.MetaAi{}))
     http.HandleFunc("/", hello)
 }
 
 func hello(w http.ResponseWriter, r *http.Request) {
     fmt.Fprintf(w, "Hello!\n\n")
-    fmt.Fprintf(w, "Currently serving:\n\n/balanced/v1\n/aggressive/v1.1\n/defensive/v1")
+    fmt.Fprintf(w, "Currently serving:\n\n/balanced/v1\n/balanced/v2\n/aggressive/v1.1\n/aggressive/v2\n/aggressive/optimal/v1\n/defensive/v1\n/defensive/v2\n/mcts/v1\n/meta/v1")
 }
@@ -0,0 +1,335 @@
+// mctsAi by Miridius
+package mctsAi
+
+import (
+    "github.com/miridius/ai/aggressiveAi"
+    common "github.com/miridius/ai/common"
+    "github.com/miridius/ai/defensiveAi"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    state "github.com/zond/stockholm-ai/state"
+    "io/ioutil"
+    "log"
+    "math"
+    "math/rand"
+    "time"
+)
+
+const (
+    // wall clock time to think per turn, if MctsAi.Budget isn't set
+    DEFAULT_BUDGET = 500 * time.Millisecond
+    // how many turns each simulation plays, if MctsAi.Horizon isn't set
+    DEFAULT_HORIZON = 12
+    // how many of those turns are in the tree, the rest are played by the default policies
+    TREE_DEPTH = 3
+    // UCB1 exploration constant, values are between 0 and 1
+    EXPLORATION = 0.3
+    // progressive widening: a tree node visited n times may have WIDENING * sqrt(n) children
+    WIDENING = 1.5
+)
+
+/*
+MCTS AI
+Searches for the best orders with Monte Carlo tree search, using the other AIs as default policies
+
+Algorithm:
+0. Add the orders of the first default policy as the first plan, so there is always something to send
+1. Until the budget runs out, play a simulation on a copy of s:
+    a. Walk down the tree, each tree node is a plan (a set of orders) for one of my turns. Pick the child with the
+       best UCB1 score, unless the node may get a new child (progressive widening), then add one and stop walking.
+       New children are the orders of each default policy first, then random mutations of the existing children.
+    b. Play my plan and the orders of the opponents, every opponent plays a random default policy, and advance with state.Next
+    c. Below the tree, a random default policy plays for me as well, until Horizon turns have been played or someone won
+    d. Score the simulation (1 for a win, 0 for a loss, otherwise my share of all units) and add it to every tree node on the way
+   The budget is checked after every call of a policy (including new children), a simulation still running when it
+   runs out is dropped. A policy call can't be interrupted, so a slow one may overrun the budget by the time it takes
+2. Send the plan of the most visited child of the root
+The tree is open loop: plans are replayed on whatever state the simulation is in, and NormalizeOrders fixes
+orders that no longer fit.
+*/
+type MctsAi struct {
+    // wall clock time to think per turn, DEFAULT_BUDGET if 0
+    Budget time.Duration
+    // maximum number of simulations per turn, unlimited (until the budget runs out) if 0
+    Iterations int
+    // turns per simulation, DEFAULT_HORIZON if 0
+    Horizon int
+    // default policies, DefaultPolicies if empty
+    Policies []AI
+    // seed for the random choices, combined with the turn so that every turn is different
+    Seed int64
+    // tells the time for the budget, time.Now if nil
+    Clock func() time.Time
+}
+
+// AI is anything that gives orders, like all the AIs in this repo
+type AI interface {
+    Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) state.Orders
+}
+
+/*
+DefaultPolicies are the AIs used in simulations if MctsAi.Policies is empty.
+The balanced AIs remember things about the game they are playing, so they are left out to keep simulations
+from messing up their memory.
+*/
+var DefaultPolicies = []AI{
+    aggressiveAi.AggressiveAi1{},
+    aggressiveAi.AggressiveAi2{},
+    aggressiveAi.OptimalAggressiveAi{},
+    defensiveAi.DefensiveAi1{},
+    defensiveAi.DefensiveAi2{},
+}
+
+// logger for the AIs playing in simulations
+var quietLogger = log.New(ioutil.Discard, "", 0)
+
+// a plan in the search tree, with the total score of all simulations that played it
+type treeNode struct {
+    orders   state.Orders
+    visits   int
+    total    float64
+    children []*treeNode
+}
+
+// the average score of the simulations through this node
+func (self *treeNode) mean() float64 {
+    if self.visits == 0 {
+        return 0
+    }
+    return self.total / float64(self.visits)
+}
+
+// true if the node may get another child
+func (self *treeNode) canWiden() bool {
+    return float64(len(self.children)) < WIDENING*math.Sqrt(float64(self.visits+1))
+}
+
+// the child with the highest UCB1 score
+func (self *treeNode) selectChild() (result *treeNode) {
+    best := math.Inf(-1)
+    for _, child := range self.children {
+        score := math.Inf(1)
+        if child.visits > 0 {
+            score = child.mean() + EXPLORATION*math.Sqrt(math.Log(float64(self.visits))/float64(child.visits))
+        }
+        if score > best {
+            best = score
+            result = child
+        }
+    }
+    return
+}
+
+// the child played the most, which is the plan we trust the most
+func (self *treeNode) bestChild() (result *treeNode) {
+    for _, child := range self.children {
+        if result == nil || child.visits > result.visits || (child.visits == result.visits && child.mean() > result.mean()) {
+            result = child
+        }
+    }
+    return
+}
+
+// everything one search needs
+type search struct {
+    me       state.PlayerId
+    root     *state.State
+    tree     *treeNode
+    horizon  int
+    policies []AI
+    random   *rand.Rand
+    clock    func() time.Time
+    deadline time.Time
+}
+
+/*
+mutate returns a random variation of orders in s: every order may be dropped or get more or fewer units,
+and sometimes a random node of mine sends some units to a random neighbour
+*/
+func (self *search) mutate(s *state.State, orders state.Orders) (result state.Orders) {
+    for _, order := range orders {
+        if self.random.Float64() < 0.2 {
+            continue
+        }
+        order.Units = int(math.Ceil(float64(order.Units) * (0.5 + self.random.Float64())))
+        result = append(result, order)
+    }
+    if self.random.Float64() < 0.5 {
+        mine := []state.NodeId{}
+        for _, nodeId := range common.SortedNodeIds(s) {
+            if s.Nodes[nodeId].Units[self.me] > 1 {
+                mine = append(mine, nodeId)
+            }
+        }
+        if len(mine) > 0 {
+            src := s.Nodes[mine[self.random.Intn(len(mine))]]
+            edges := common.SortedEdges(src)
+            if len(edges) > 0 {
+                result = append(result, state.Order{
+                    Src:   src.Id,
+                    Dst:   edges[self.random.Intn(len(edges))].Dst,
+                    Units: 1 + self.random.Intn(src.Units[self.me]),
+                })
+            }
+        }
+    }
+    result, _ = common.NormalizeOrders(self.me, s, result)
+    return
+}
+
+// adds a new child to node, with orders for s
+func (self *search) expand(node *treeNode, s *state.State) (child *treeNode) {
+    child = &treeNode{}
+    if index := len(node.children); index < len(self.policies) {
+        child.orders = self.policies[index].Orders(quietLogger, self.me, s)
+    } else {
+        child.orders = self.mutate(s, node.children[self.random.Intn(len(node.children))].orders)
+    }
+    node.children = append(node.children, child)
+    return
+}
+
+// true until the deadline, checked after every call of a policy so that the search stops as soon as one returns late
+func (self *search) inTime() bool {
+    return self.clock().Before(self.deadline)
+}
+
+// the score of a finished simulation
+func (self *search) score(s *state.State, winner *state.PlayerId) float64 {
+    if winner != nil {
+        if *winner == self.me {
+            return 1
+        }
+        return 0
+    }
+    total, mine := 0, 0
+    for _, standing := range common.Standings(s) {
+        total += standing.Units()
+        if standing.Player == self.me {
+            mine = standing.Units()
+        }
+    }
+    if total == 0 {
+        return 0
+    }
+    return float64(mine) / float64(total)
+}
+
+// plays one simulation and records its score in the tree, returns false if the budget ran out before it finished
+func (self *search) iterate() (finished bool, err error) {
+    s, err := common.CopyState(self.root)
+    if err != nil {
+        return
+    }
+    // every opponent plays a random policy for the whole simulation
+    opponents := []state.PlayerId{}
+    policies := make(map[state.PlayerId]AI)
+    for _, standing := range common.Standings(s) {
+        if standing.Player != self.me {
+            opponents = append(opponents, standing.Player)
+            policies[standing.Player] = self.policies[self.random.Intn(len(self.policies))]
+        }
+    }
+    myPolicy := self.policies[self.random.Intn(len(self.policies))]
+
+    path := []*treeNode{self.tree}
+    node := self.tree
+    inTree := true
+    var winner *state.PlayerId
+    for turn := 0; turn < self.horizon && winner == nil; turn++ {
+        orders := make(map[state.PlayerId]state.Orders, len(opponents)+1)
+        if inTree && turn < TREE_DEPTH {
+            if node.canWiden() {
+                node = self.expand(node, s)
+                // a new node is played once and then the default policies take over
+                inTree = false
+                if !self.inTime() {
+                    return
+                }
+            } else {
+                node = node.selectChild()
+            }
+            path = append(path, node)
+            orders[self.me] = node.orders
+        } else {
+            orders[self.me] = myPolicy.Orders(quietLogger, self.me, s)
+            if !self.inTime() {
+                return
+            }
+        }
+        for _, player := range opponents {
+            orders[player] = policies[player].Orders(quietLogger, player, s)
+            if !self.inTime() {
+                return
+            }
+        }
+        winner = s.Next(quietLogger, orders)
+    }
+
+    value := self.score(s, winner)
+    for _, node := range path {
+        node.visits++
+        node.total += value
+    }
+    return true, nil
+}
+
+/*
+Orders will analyze all nodes in s and return orders for each one
+*/
+func (self MctsAi) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) (result state.Orders) {
+
+    logger.Printf("MctsAi calculating orders for player: %v", me)
+
+    search := &search{
+        me:       me,
+        root:     s,
+        tree:     &treeNode{},
+        horizon:  DEFAULT_HORIZON,
+        policies: DefaultPolicies,
+        random:   rand.New(rand.NewSource(self.Seed + int64(s.Turn))),
+        clock:    time.Now,
+    }
+    if self.Clock != nil {
+        search.clock = self.Clock
+    }
+    budget := DEFAULT_BUDGET
+    if self.Budget > 0 {
+        budget = self.Budget
+    }
+    search.deadline = search.clock().Add(budget)
+    if self.Horizon > 0 {
+        search.horizon = self.Horizon
+    }
+    if len(self.Policies) > 0 {
+        search.policies = self.Policies
+    }
+
+    // 0. what the first policy says, in case no simulation finishes in time
+    search.expand(search.tree, s)
+
+    // 1. keep simulating until the budget runs out
+    iterations := 0
+    for search.inTime() && (self.Iterations == 0 || iterations < self.Iterations) {
+        finished, err := search.iterate()
+        if err != nil {
+            logger.Printf("MctsAi simulation failed: %v", err)
+            break
+        }
+        if !finished {
+            break
+        }
+        iterations++
+    }
+
+    // 2. the best plan so far
+    best := search.tree.bestChild()
+    logger.Printf("%v simulations, best plan played %v times, scoring %.3f", iterations, best.visits, best.mean())
+    result = best.orders
+
+    // drop or fix any invalid orders
+    result, changes := common.NormalizeOrders(me, s, result)
+    for _, change := range changes {
+        logger.Printf("NormalizeOrders %v", change)
+    }
+    return
+}
@@ -0,0 +1,116 @@
+package mctsAi
+
+import (
+    "github.com/miridius/ai/aggressiveAi"
+    "github.com/miridius/ai/aitest"
+    common "github.com/miridius/ai/common"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    "github.com/zond/stockholm-ai/state"
+    "io/ioutil"
+    "log"
+    "testing"
+    "time"
+)
+
+// a clock that moves on by step every time it is read, and counts how often it was read past deadline
+type fakeClock struct {
+    now      time.Time
+    step     time.Duration
+    deadline time.Time
+    late     int
+}
+
+func (self *fakeClock) read() time.Time {
+    self.now = self.now.Add(self.step)
+    if !self.now.Before(self.deadline) {
+        self.late++
+    }
+    return self.now
+}
+
+func TestBudget(t *testing.T) {
+    logger := log.New(ioutil.Discard, "", 0)
+    s := aitest.SeededState(1, []state.PlayerId{"a", "b", "c"})
+    // every look at the clock takes 1ms, so 10ms is not enough to finish a single simulation of 12 turns
+    for _, budget := range []time.Duration{10 * time.Millisecond, 100 * time.Millisecond} {
+        start := time.Unix(0, 0)
+        clock := &fakeClock{now: start, step: time.Millisecond, deadline: start.Add(time.Millisecond + budget)}
+        orders := MctsAi{Budget: budget, Horizon: 12, Seed: 1, Clock: clock.read}.Orders(logger, "a", s)
+        // the first look at the clock past the deadline ends the search, so the whole budget is used and no more
+        if clock.late != 1 {
+            t.Errorf("budget %v, expected to stop at the deadline, but read the clock %v times past it", budget, clock.late)
+        }
+        if spent := clock.now.Sub(start); spent != budget+time.Millisecond {
+            t.Errorf("budget %v, but thinking took %v", budget, spent-time.Millisecond)
+        }
+        if len(orders) == 0 {
+            t.Errorf("budget %v, expected some orders", budget)
+        }
+        if _, changes := common.NormalizeOrders("a", s, orders); len(changes) > 0 {
+            t.Errorf("budget %v, expected valid orders, got %v", budget, changes)
+        }
+    }
+}
+
+// a policy that takes delay on clock every time it gives orders, and counts the calls started past the deadline
+type slowPolicy struct {
+    clock *fakeClock
+    delay time.Duration
+    late  *int
+}
+
+func (self slowPolicy) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) state.Orders {
+    if !self.clock.now.Before(self.clock.deadline) {
+        *self.late++
+    }
+    self.clock.now = self.clock.now.Add(self.delay)
+    return aggressiveAi.AggressiveAi1{}.Orders(logger, me, s)
+}
+
+func TestSlowPolicy(t *testing.T) {
+    logger := log.New(ioutil.Discard, "", 0)
+    s := aitest.SeededState(1, []state.PlayerId{"a", "b", "c"})
+    // 30ms per policy call against a budget of 100ms ends the search in the middle of a simulation,
+    // and 50ms against a budget of 10ms already overruns it with the first plan
+    for _, budget := range []time.Duration{100 * time.Millisecond, 10 * time.Millisecond} {
+        start := time.Unix(0, 0)
+        clock := &fakeClock{now: start, step: time.Millisecond, deadline: start.Add(time.Millisecond + budget)}
+        late := 0
+        delay := 30 * time.Millisecond
+        if budget < delay {
+            delay = 50 * time.Millisecond
+        }
+        policy := slowPolicy{clock: clock, delay: delay, late: &late}
+        orders := MctsAi{Budget: budget, Horizon: 12, Seed: 1, Clock: clock.read, Policies: []AI{policy}}.Orders(logger, "a", s)
+        // the first look at the clock after the slow call ends the search, so nothing more is started
+        if late != 0 {
+            t.Errorf("budget %v, expected no policy calls past the deadline, got %v", budget, late)
+        }
+        if clock.late != 1 {
+            t.Errorf("budget %v, expected to stop at the deadline, but read the clock %v times past it", budget, clock.late)
+        }
+        if overrun := clock.now.Sub(clock.deadline); overrun > delay+time.Millisecond {
+            t.Errorf("budget %v, expected to overrun by at most one call of %v, got %v", budget, delay, overrun)
+        }
+        if len(orders) == 0 {
+            t.Errorf("budget %v, expected some orders", budget)
+        }
+    }
+}
+
+func TestOrdersDeterministic(t *testing.T) {
+    // with a seed and a fixed number of simulations the same state must always give the same orders
+    aitest.CheckDeterministic(t, 3, MctsAi{Budget: time.Minute, Iterations: 20, Horizon: 8, Seed: 42})
+}
+
+func TestBeatsAggressive(t *testing.T) {
+    // a seeded series of games against AggressiveAi1, small enough to play in a few seconds
+    wins := aitest.Series(t, 5, 300, map[state.PlayerId]aitest.AI{
+        // a fixed number of simulations instead of a budget, so the result doesn't depend on the machine
+        "mcts":       MctsAi{Budget: time.Minute, Iterations: 10, Horizon: 6, Seed: 1},
+        "aggressive": aggressiveAi.AggressiveAi1{},
+    })
+    if wins["mcts"] <= wins["aggressive"] {
+        t.Errorf("expected MctsAi to win more games than AggressiveAi1, got %v", wins)
+    }
+}
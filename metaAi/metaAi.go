@@ -0,0 +1,96 @@
+// metaAi by Miridius
+package metaAi
+
+import (
+    "github.com/miridius/ai/aggressiveAi"
+    "github.com/miridius/ai/balancedAi"
+    common "github.com/miridius/ai/common"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    state "github.com/zond/stockholm-ai/state"
+)
+
+// for how many turns, starting with the hand-off itself, we stop the new strategy from undoing the moves of the old one
+const HANDOFF_TURNS = 3
+
+// AI is anything that gives orders, like all the AIs in this repo
+type AI interface {
+    Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) state.Orders
+}
+
+// Strategies is the AI that plays each phase
+var Strategies = map[Phase]AI{
+    OPENING:     balancedAi.BalancedAi2{},
+    CONTACT:     aggressiveAi.AggressiveAi2{},
+    DOMINANCE:   aggressiveAi.OptimalAggressiveAi{},
+    DESPERATION: aggressiveAi.AggressiveAi1{},
+}
+
+/*
+Meta AI
+Doesn't play by itself, but works out which phase the game is in and hands each turn to the AI that plays it best:
+- Opening (unclaimed nodes left, no enemies near me): balanced v2, which grows well on a quiet map
+- Contact (enemies near me, nobody clearly ahead): aggressive v2, which wins the early fights
+- Dominance (I have DOMINANCE_RATIO times the units of the strongest opponent): optimal aggressive, which plans
+  all attacks together to finish the game. Holding the lead with defensive v1 instead loses more series against
+  aggressive v1 than it wins, see TestMetaOrders
+- Desperation (the strongest opponent has DESPERATION_RATIO times my units): aggressive v1, which goes all in
+
+Algorithm:
+1. Detect the phase from the standings and the number of unclaimed nodes (detectPhase)
+2. Smooth it: a new phase only takes over after being detected PHASE_TURNS turns in a row
+3. Get the orders of the strategy for the phase
+4. For HANDOFF_TURNS turns from a hand-off on, drop orders sending units straight back along an edge the old
+   strategy sent units down on its last turn, so units don't go back and forth while the new strategy takes over
+*/
+type MetaAi struct{}
+
+// true if order sends units back along the edge of one of orders
+func reverses(order state.Order, orders state.Orders) bool {
+    for _, previous := range orders {
+        if previous.Src == order.Dst && previous.Dst == order.Src {
+            return true
+        }
+    }
+    return false
+}
+
+/*
+Orders will analyze all nodes in s and return orders for each one
+*/
+func (self MetaAi) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) (result state.Orders) {
+
+    logger.Printf("MetaAi calculating orders for player: %v", me)
+
+    tracker := getPhaseTracker(me, s)
+    tracker.mutex.Lock()
+    defer tracker.mutex.Unlock()
+
+    // 1. and 2. which phase are we in?
+    detected := detectPhase(me, s, common.GetTopology(s).Distances)
+    phase := tracker.observe(s.Turn, detected)
+    logger.Printf("Detected %v, playing %v since turn %v", detected, phase, tracker.since)
+
+    // 3. let the strategy for the phase play
+    orders := Strategies[phase].Orders(logger, me, s)
+
+    // 4. smooth the hand-off
+    if tracker.handedOff && s.Turn >= tracker.since && s.Turn-tracker.since < HANDOFF_TURNS {
+        for _, order := range orders {
+            if reverses(order, tracker.oldOrders) {
+                logger.Printf("Hand-off: not sending %v units back from %v to %v", order.Units, order.Src, order.Dst)
+                continue
+            }
+            result = append(result, order)
+        }
+    } else {
+        result = orders
+    }
+
+    // drop or fix any invalid orders
+    result, changes := common.NormalizeOrders(me, s, result)
+    for _, change := range changes {
+        logger.Printf("NormalizeOrders %v", change)
+    }
+    tracker.lastOrders = result
+    return
+}
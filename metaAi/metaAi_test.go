@@ -0,0 +1,196 @@
+package metaAi
+
+import (
+    "github.com/miridius/ai/aggressiveAi"
+    "github.com/miridius/ai/aitest"
+    common "github.com/miridius/ai/common"
+    "github.com/miridius/ai/defensiveAi"
+    stockholmCommon "github.com/zond/stockholm-ai/common"
+    "github.com/zond/stockholm-ai/state"
+    "io/ioutil"
+    "log"
+    "testing"
+)
+
+// a line a - b - c - d - e - f, 2 turns between neighbours, with me on a and them on f
+func lineState() *state.State {
+    ids := []state.NodeId{"a", "b", "c", "d", "e", "f"}
+    s := aitest.NewState(50, ids...)
+    for i := 1; i < len(ids); i++ {
+        aitest.Connect(s, ids[i-1], ids[i], 2)
+    }
+    s.Nodes["a"].Units["me"] = 10
+    s.Nodes["f"].Units["them"] = 10
+    return s
+}
+
+func TestDetectPhase(t *testing.T) {
+    s := lineState()
+    distances := common.NewDistanceMatrix(s)
+    check := func(expected Phase) {
+        if phase := detectPhase("me", s, distances); phase != expected {
+            t.Errorf("expected %v, got %v", expected, phase)
+        }
+    }
+
+    // far apart with unclaimed nodes in between
+    check(OPENING)
+
+    // them within reach
+    s.Nodes["f"].Units["them"] = 0
+    s.Nodes["c"].Units["them"] = 10
+    check(CONTACT)
+
+    // even far away, once nothing is left to claim we are fighting
+    s.Nodes["c"].Units["them"] = 0
+    s.Nodes["f"].Units["them"] = 10
+    for _, id := range []state.NodeId{"b", "c", "d", "e"} {
+        s.Nodes[id].Units["me"] = 1
+    }
+    check(CONTACT)
+
+    // way ahead, units on edges count too
+    s.Nodes["a"].Edges["b"].Units[0] = state.Units{"me": 20}
+    check(DOMINANCE)
+
+    // way behind
+    s.Nodes["a"].Edges["b"].Units[0] = nil
+    s.Nodes["f"].Units["them"] = 40
+    check(DESPERATION)
+}
+
+func TestPhaseTracker(t *testing.T) {
+    tracker := &phaseTracker{}
+    check := func(turn int, detected, expected Phase) {
+        if phase := tracker.observe(turn, detected); phase != expected {
+            t.Errorf("turn %v, detected %v: expected to play %v, got %v", turn, detected, expected, phase)
+        }
+    }
+    check(0, OPENING, OPENING)
+    // a new phase takes over after PHASE_TURNS turns in a row
+    check(1, CONTACT, OPENING)
+    check(2, CONTACT, OPENING)
+    check(2, CONTACT, OPENING) // same turn again
+    check(3, CONTACT, CONTACT)
+    if !tracker.handedOff || tracker.since != 3 {
+        t.Errorf("expected a hand-off on turn 3, got %+v", tracker)
+    }
+    // blips don't
+    check(4, DOMINANCE, CONTACT)
+    check(5, DOMINANCE, CONTACT)
+    check(6, CONTACT, CONTACT)
+    check(7, DOMINANCE, CONTACT)
+    check(8, DESPERATION, CONTACT)
+    check(9, DOMINANCE, CONTACT)
+}
+
+func TestPhaseTrackerPerGame(t *testing.T) {
+    logger := log.New(ioutil.Discard, "", 0)
+    // a game on the line that has moved on from the opening to contact
+    s := lineState()
+    s.Nodes["a"].Units["me"] = 15
+    tracker := getPhaseTracker("me", s)
+    tracker.observe(s.Turn, OPENING)
+    for turn := 1; turn <= PHASE_TURNS; turn++ {
+        s.Next(logger, map[state.PlayerId]state.Orders{})
+        if again := getPhaseTracker("me", s); again != tracker {
+            t.Fatalf("turn %v: expected the tracker of the same game", s.Turn)
+        }
+        tracker.observe(s.Turn, CONTACT)
+    }
+    if tracker.phase != CONTACT || !tracker.handedOff {
+        t.Fatalf("expected the first game to have handed off to contact, got %+v", tracker)
+    }
+
+    // a new game on the same map starts over
+    other := getPhaseTracker("me", lineState())
+    if other == tracker {
+        t.Fatalf("expected a new tracker for a new game")
+    }
+    if phase := other.observe(0, OPENING); phase != OPENING || other.handedOff {
+        t.Errorf("expected the new game to start over in the opening, got %+v", other)
+    }
+}
+
+func TestReverses(t *testing.T) {
+    last := state.Orders{{Src: "a", Dst: "b", Units: 5}}
+    if !reverses(state.Order{Src: "b", Dst: "a", Units: 3}, last) {
+        t.Errorf("expected b -> a to reverse %v", last)
+    }
+    for _, order := range []state.Order{{Src: "a", Dst: "b", Units: 3}, {Src: "b", Dst: "c", Units: 3}} {
+        if reverses(order, last) {
+            t.Errorf("expected %v not to reverse %v", order, last)
+        }
+    }
+}
+
+// an AI that always gives the same orders
+type scripted state.Orders
+
+func (self scripted) Orders(logger stockholmCommon.Logger, me state.PlayerId, s *state.State) state.Orders {
+    return state.Orders(self)
+}
+
+func TestHandOff(t *testing.T) {
+    // the opening sends units from a to b, contact sends them back and on to c
+    defer func(strategies map[Phase]AI) { Strategies = strategies }(Strategies)
+    Strategies = map[Phase]AI{
+        OPENING: scripted{{Src: "a", Dst: "b", Units: 5}},
+        CONTACT: scripted{{Src: "b", Dst: "a", Units: 3}, {Src: "b", Dst: "c", Units: 2}},
+    }
+    logger := log.New(ioutil.Discard, "", 0)
+    s := lineState()
+    s.Nodes["a"].Units["me"] = 5
+    s.Nodes["b"].Units["me"] = 5
+    sent := func() (result map[state.NodeId]int) {
+        result = make(map[state.NodeId]int)
+        for _, order := range (MetaAi{}).Orders(logger, "me", s) {
+            result[order.Dst] += order.Units
+        }
+        return
+    }
+    if orders := sent(); orders["b"] != 5 {
+        t.Fatalf("expected to play the opening, got %v", orders)
+    }
+
+    // them moving close is detected every turn from now on, so contact takes over after PHASE_TURNS turns
+    s.Nodes["f"].Units["them"] = 0
+    s.Nodes["d"].Units["them"] = 10
+    handOff := s.Turn + PHASE_TURNS
+    for s.Turn++; s.Turn < handOff; s.Turn++ {
+        if orders := sent(); orders["b"] != 5 {
+            t.Fatalf("turn %v: expected to play the opening until the hand-off, got %v", s.Turn, orders)
+        }
+    }
+    // contact must not send units back along the edge the opening used, until HANDOFF_TURNS have passed
+    for ; s.Turn < handOff+HANDOFF_TURNS; s.Turn++ {
+        if orders := sent(); orders["a"] != 0 || orders["c"] != 2 {
+            t.Errorf("turn %v: expected only b -> c right after the hand-off, got %v", s.Turn, orders)
+        }
+    }
+    if orders := sent(); orders["a"] != 3 || orders["c"] != 2 {
+        t.Errorf("turn %v: expected contact to play all its orders, got %v", s.Turn, orders)
+    }
+}
+
+func TestMetaOrders(t *testing.T) {
+    // meta against the AI it uses when desperate, on a fixed set of maps, meta must win more games
+    series := func() map[state.PlayerId]int {
+        return aitest.Series(t, 20, 500, map[state.PlayerId]aitest.AI{
+            "meta":       MetaAi{},
+            "aggressive": aggressiveAi.AggressiveAi1{},
+        })
+    }
+    wins := series()
+    if wins["meta"] <= wins["aggressive"] {
+        t.Errorf("expected meta to win more games than aggressive v1, got %v", wins)
+    }
+
+    // and holding a lead with DefensiveAi1 instead of the strategy for DOMINANCE must win fewer
+    chosen := Strategies[DOMINANCE]
+    defer func() { Strategies[DOMINANCE] = chosen }()
+    Strategies[DOMINANCE] = defensiveAi.DefensiveAi1{}
+    if defensive := series(); defensive["meta"] >= wins["meta"] {
+        t.Errorf("expected %T to win more games than DefensiveAi1 when dominating, got %v and %v", chosen, wins, defensive)
+    }
+}
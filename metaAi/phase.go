@@ -0,0 +1,170 @@
+package metaAi
+
+import (
+    common "github.com/miridius/ai/common"
+    state "github.com/zond/stockholm-ai/state"
+    "sync"
+)
+
+const (
+    // I dominate once I have this many times the units of the strongest opponent
+    DOMINANCE_RATIO = common.RUNAWAY_RATIO
+    // I am desperate once the strongest opponent has this many times my units
+    DESPERATION_RATIO = 2.0
+    // how many turns in a row a new phase must be detected before we hand over to it
+    PHASE_TURNS = 3
+)
+
+// Phase is the stage of the game as seen by one player
+type Phase int
+
+const (
+    OPENING     Phase = iota // unclaimed nodes left and no enemies near me, grab as much as possible
+    CONTACT                  // enemies near me and nobody clearly ahead
+    DOMINANCE                // I am clearly ahead
+    DESPERATION              // someone is clearly ahead of me
+)
+
+func (self Phase) String() string {
+    switch self {
+    case OPENING:
+        return "opening"
+    case CONTACT:
+        return "contact"
+    case DOMINANCE:
+        return "dominance"
+    case DESPERATION:
+        return "desperation"
+    }
+    return "unknown"
+}
+
+// the number of nodes nobody has units on
+func unclaimed(s *state.State) (result int) {
+    for _, node := range s.Nodes {
+        claimed := false
+        for _, numUnits := range node.Units {
+            if numUnits > 0 {
+                claimed = true
+            }
+        }
+        if !claimed {
+            result++
+        }
+    }
+    return
+}
+
+// true if enemy units are within common.NEIGHBOUR_TURNS of any node I hold, either on a node or on their way
+func inContact(me state.PlayerId, s *state.State, distances *common.DistanceMatrix) bool {
+    nodeIds := common.SortedNodeIds(s)
+    unitCounts := common.CountAllUnits(me, s)
+    for _, nodeId := range nodeIds {
+        if s.Nodes[nodeId].Units[me] <= 0 {
+            continue
+        }
+        if unitCounts[nodeId].EnemyUnits > 0 {
+            return true
+        }
+        for _, other := range nodeIds {
+            if distances.Distance(other, nodeId) <= common.NEIGHBOUR_TURNS {
+                for player, numUnits := range s.Nodes[other].Units {
+                    if player != me && numUnits > 0 {
+                        return true
+                    }
+                }
+            }
+        }
+    }
+    return false
+}
+
+/*
+detectPhase decides which phase the game is in for me:
+1. DOMINANCE if I have DOMINANCE_RATIO times the units of the strongest opponent
+2. DESPERATION if the strongest opponent has DESPERATION_RATIO times my units
+3. OPENING if there are unclaimed nodes left and no enemies are near me
+4. CONTACT otherwise
+*/
+func detectPhase(me state.PlayerId, s *state.State, distances *common.DistanceMatrix) Phase {
+    standings := common.Standings(s)
+    mine, _ := standings.Get(me)
+    strongest := 0
+    for _, standing := range standings {
+        if standing.Player != me {
+            strongest = common.Max(strongest, standing.Units())
+        }
+    }
+    myUnits := float64(mine.Units())
+    switch {
+    case myUnits >= DOMINANCE_RATIO*float64(strongest):
+        return DOMINANCE
+    case float64(strongest) >= DESPERATION_RATIO*myUnits:
+        return DESPERATION
+    case unclaimed(s) > 0 && !inContact(me, s, distances):
+        return OPENING
+    }
+    return CONTACT
+}
+
+/*
+phaseTracker smooths the detected phase of one player in one game, so that a single odd turn doesn't make us
+hand over to another strategy and back: a new phase only takes over once it has been detected PHASE_TURNS turns in a row.
+It also remembers the orders we sent on the last turn, and the last orders of the strategy we handed over from, see MetaAi.
+*/
+type phaseTracker struct {
+    mutex      sync.Mutex
+    observed   bool
+    lastTurn   int
+    phase      Phase
+    since      int          // turn the current phase took over
+    handedOff  bool         // true if the current phase took over from another one
+    candidate  Phase        // phase detected on the last turn(s) but not taken over yet
+    seen       int          // turns in a row candidate was detected
+    lastOrders state.Orders // the orders we sent on lastTurn
+    oldOrders  state.Orders // the orders we sent on the turn before the current phase took over
+}
+
+// the phase tracker of me in the game that s belongs to
+func getPhaseTracker(me state.PlayerId, s *state.State) *phaseTracker {
+    return common.GameOf(s).Value("metaAi/phase/"+string(me), func() interface{} {
+        return &phaseTracker{}
+    }).(*phaseTracker)
+}
+
+/*
+observe records the phase detected on turn and returns the phase to play. Repeated calls for the same turn
+return the same phase. A new game on the same map gets a new tracker from common.GameOf.
+Must be called with the mutex held.
+*/
+func (self *phaseTracker) observe(turn int, detected Phase) Phase {
+    if !self.observed {
+        self.observed = true
+        self.phase = detected
+        self.since = turn
+        self.handedOff = false
+        self.candidate = detected
+        self.seen = 0
+        self.lastOrders = nil
+    } else if turn > self.lastTurn {
+        if detected == self.phase {
+            self.seen = 0
+        } else {
+            if detected == self.candidate {
+                self.seen++
+            } else {
+                self.candidate = detected
+                self.seen = 1
+            }
+            if self.seen >= PHASE_TURNS {
+                self.phase = detected
+                self.since = turn
+                self.handedOff = true
+                self.seen = 0
+                self.oldOrders = self.lastOrders
+            }
+        }
+    }
+    self.lastTurn = turn
+    return self.phase
+}